@@ -0,0 +1,50 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/zpxio/mdsite/pkg/check"
+	"github.com/zpxio/mdsite/pkg/config"
+	"os"
+)
+
+// runCheck validates the configured sites, and prints a JSON report. It
+// returns a non-zero exit code if any errors were found.
+func runCheck(args []string) int {
+	conf := config.Create()
+	config.SetupFlags(conf)
+	conf.LoadAll(args)
+
+	report := check.Run(conf)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(report)
+	if err != nil {
+		log.Errorf("Failed to write check report: %s", err)
+		return 2
+	}
+
+	if !report.Ok {
+		log.Errorf("Site check failed")
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/spf13/pflag"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/server"
+	"os"
+)
+
+const (
+	graphDot  = "dot"
+	graphJson = "json"
+)
+
+// runGraph prints the link graph of a site, or the list of its orphaned
+// pages.
+func runGraph(args []string) int {
+	format := pflag.String("format", graphJson, "Graph output format: dot or json")
+	orphans := pflag.Bool("orphans", false, "List the pages with no inbound links that are missing from order.yml, as JSON")
+
+	conf := config.Create()
+	config.SetupFlags(conf)
+	conf.LoadAll(args)
+
+	if *format != graphDot && *format != graphJson {
+		log.Errorf("Unknown graph format: %s", *format)
+		return 2
+	}
+	if conf.SitesFile != "" {
+		log.Errorf("The graph is built for a single site, given by --site and --config")
+		return 2
+	}
+
+	sc, err := config.LoadSiteConfig(conf)
+	if err != nil {
+		log.Errorf("Failed to load site configuration: %s", err)
+		return 1
+	}
+
+	st, err := server.NewSite(conf, sc)
+	if err != nil {
+		log.Errorf("Failed to set up site: %s", err)
+		return 1
+	}
+
+	index, err := st.ReIndex()
+	if err != nil {
+		log.Errorf("Failed to index site: %s", err)
+		return 1
+	}
+	pages := st.RenderedPages(index)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	switch {
+	case *orphans:
+		err = enc.Encode(server.NewPageRefs(pages.Orphans()))
+	case *format == graphDot:
+		err = pages.Graph(sc.Title).WriteDot(os.Stdout)
+	default:
+		err = enc.Encode(pages.Graph(sc.Title))
+	}
+	if err != nil {
+		log.Errorf("Failed to write graph: %s", err)
+		return 2
+	}
+
+	return 0
+}
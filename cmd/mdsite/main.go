@@ -22,7 +22,6 @@ import (
 	"github.com/apex/log/handlers/text"
 	"github.com/zpxio/mdsite/pkg/config"
 	"github.com/zpxio/mdsite/pkg/server"
-	"github.com/zpxio/mdsite/pkg/site"
 	"os"
 	"os/signal"
 	"syscall"
@@ -31,30 +30,63 @@ import (
 func main() {
 	log.SetHandler(text.New(os.Stderr))
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "graph":
+			os.Exit(runGraph(os.Args[2:]))
+		}
+	}
+
 	log.Infof("Starting up...")
 
 	conf := config.Create()
 	config.SetupFlags(conf)
 	conf.Load()
-	config.SetGlobal(conf)
+	server.SetupLogging(conf)
 
-	// Load config
-	var err error
-	conf.SiteConfig, err = config.LoadSiteConfig()
-	if err != nil {
-		log.Errorf("Failed to load site configuration: %s", err)
-		panic("Cannot load site config")
+	if conf.PrintConfig {
+		err := conf.WriteSettings(os.Stdout)
+		if err != nil {
+			log.Fatalf("Failed to print settings: %s", err)
+		}
+		return
 	}
 
-	// Index the Site
-	site.Index()
+	s := server.CreateDispatcher(conf)
+
+	if conf.SitesFile != "" {
+		// Load and index every hosted site
+		err := s.AttachSitesFile(conf.SitesFile)
+		if err != nil {
+			log.Errorf("Failed to load sites: %s", err)
+			panic("Cannot load sites")
+		}
+	} else {
+		// Load config
+		var err error
+		conf.SiteConfig, err = config.LoadSiteConfig(conf)
+		if err != nil {
+			log.Errorf("Failed to load site configuration: %s", err)
+			panic("Cannot load site config")
+		}
+
+		// Index the Site
+		st, err := server.NewSite(conf, conf.SiteConfig)
+		if err != nil {
+			log.Errorf("Failed to set up site: %s", err)
+			panic("Cannot set up site")
+		}
+		st.Index()
+		s.AttachSite(st)
+	}
 
 	// Set up signal monitoring
 	termSignals := make(chan os.Signal, 1)
 	exitChan := make(chan bool, 1)
 	signal.Notify(termSignals, syscall.SIGTERM, syscall.SIGINT)
 
-	s := server.CreateDispatcher(conf)
 	s.Start()
 
 	go func() {
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/zpxio/mdsite/pkg/util"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// AnyUser in a rule's user list allows every authenticated user.
+const AnyUser = "*"
+
+// AccessList restricts site paths to a set of users and groups. Rules are
+// checked in order and the first rule matching a path decides access. Paths
+// without a matching rule are readable by everyone.
+type AccessList struct {
+	Rules []AccessRule `yaml:"rules"`
+}
+
+type AccessRule struct {
+	Path   string   `yaml:"path"`
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+}
+
+func LoadAccessList(path string) (*AccessList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	acl := AccessList{}
+	err = yaml.UnmarshalStrict(data, &acl)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range acl.Rules {
+		if r.Path == "" {
+			return nil, fmt.Errorf("access rule %d has no path", i)
+		}
+	}
+
+	log.Infof("Loaded %d access rule(s) from: %s", len(acl.Rules), path)
+
+	return &acl, nil
+}
+
+func (r AccessRule) permits(u *User) bool {
+	if u == nil {
+		return false
+	}
+
+	for _, name := range r.Users {
+		if name == AnyUser || name == u.Name {
+			return true
+		}
+	}
+
+	for _, g := range r.Groups {
+		if u.InGroup(g) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allowed checks whether a user (nil for anonymous) may read a site path.
+func (a *AccessList) Allowed(u *User, sitePath string) bool {
+	if a == nil {
+		return true
+	}
+
+	for _, r := range a.Rules {
+		if util.PathMatches(r.Path, sitePath) {
+			return r.permits(u)
+		}
+	}
+
+	return true
+}
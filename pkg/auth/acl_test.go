@@ -0,0 +1,69 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type AccessListSuite struct {
+	suite.Suite
+	acl *AccessList
+}
+
+func TestAccessListSuite(t *testing.T) {
+	suite.Run(t, new(AccessListSuite))
+}
+
+func (s *AccessListSuite) SetupTest() {
+	s.acl = &AccessList{
+		Rules: []AccessRule{
+			{Path: "/security/public/", Users: []string{AnyUser}},
+			{Path: "/security/", Users: []string{"alice"}, Groups: []string{"secops"}},
+			{Path: "/runbooks/*/private", Groups: []string{"oncall"}},
+			{Path: "/closed/"},
+		},
+	}
+}
+
+func (s *AccessListSuite) TestAllowed() {
+	alice := &User{Name: "alice"}
+	bob := &User{Name: "bob", Groups: []string{"secops"}}
+	carol := &User{Name: "carol", Groups: []string{"oncall"}}
+
+	s.True(s.acl.Allowed(nil, "/index"))
+	s.False(s.acl.Allowed(nil, "/security/keys"))
+	s.True(s.acl.Allowed(alice, "/security/keys"))
+	s.True(s.acl.Allowed(bob, "/security/keys"))
+	s.False(s.acl.Allowed(carol, "/security/keys"))
+
+	s.True(s.acl.Allowed(carol, "/security/public/faq"))
+	s.False(s.acl.Allowed(nil, "/security/public/faq"))
+
+	s.True(s.acl.Allowed(carol, "/runbooks/db/private"))
+	s.False(s.acl.Allowed(alice, "/runbooks/db/private"))
+	s.True(s.acl.Allowed(alice, "/runbooks/db/public"))
+
+	s.False(s.acl.Allowed(alice, "/closed/anything"))
+}
+
+func (s *AccessListSuite) TestNilList() {
+	var acl *AccessList
+
+	s.True(acl.Allowed(nil, "/security/keys"))
+}
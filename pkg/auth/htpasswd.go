@@ -0,0 +1,174 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/apex/log"
+	"github.com/zpxio/mdsite/pkg/util"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+const htpasswdCheckInterval = 5 * time.Second
+
+// Htpasswd authenticates users against an Apache style htpasswd file. The
+// file is reloaded when it changes. Bcrypt, SHA-crypt ($5$, $6$) and {SHA}
+// entries are supported.
+type Htpasswd struct {
+	watch *util.FileWatch
+
+	lock      sync.RWMutex
+	lastCheck time.Time
+	users     map[string]string
+}
+
+func LoadHtpasswd(path string) (*Htpasswd, error) {
+	h := Htpasswd{
+		watch:     util.WatchFile(path),
+		lastCheck: time.Now(),
+	}
+
+	err := h.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+func (h *Htpasswd) load() error {
+	data, err := ioutil.ReadFile(h.watch.Path)
+	if err != nil {
+		return err
+	}
+
+	users := parseHtpasswd(data)
+
+	h.lock.Lock()
+	h.users = users
+	h.lock.Unlock()
+
+	log.Infof("Loaded %d user(s) from: %s", len(users), h.watch.Path)
+
+	return nil
+}
+
+func parseHtpasswd(data []byte) map[string]string {
+	users := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sep := strings.IndexByte(line, ':')
+		if sep <= 0 {
+			log.Warnf("Ignoring malformed htpasswd entry")
+			continue
+		}
+
+		name, hashed := line[:sep], line[sep+1:]
+		if !supportedHash(hashed) {
+			log.Warnf("Ignoring htpasswd entry for [%s]: unsupported hash format", name)
+			continue
+		}
+
+		users[name] = hashed
+	}
+
+	return users
+}
+
+func supportedHash(hashed string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$", "$5$", "$6$", "{SHA}"} {
+		if strings.HasPrefix(hashed, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Refresh reloads the file if it has changed. A file which fails to load
+// leaves the previous users in place.
+func (h *Htpasswd) Refresh() {
+	h.lock.Lock()
+	h.lastCheck = time.Now()
+	changed := h.watch.Changed()
+	h.lock.Unlock()
+
+	if !changed {
+		return
+	}
+
+	err := h.load()
+	if err != nil {
+		log.Errorf("Failed to reload htpasswd file, keeping the previous users: %s", err)
+	}
+}
+
+func (h *Htpasswd) Authenticate(user string, password string) bool {
+	h.lock.RLock()
+	stale := time.Since(h.lastCheck) > htpasswdCheckInterval
+	h.lock.RUnlock()
+
+	if stale {
+		h.Refresh()
+	}
+
+	h.lock.RLock()
+	hashed, ok := h.users[user]
+	h.lock.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	return checkPassword(hashed, password)
+}
+
+func checkPassword(hashed string, password string) bool {
+	switch {
+	case strings.HasPrefix(hashed, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+	case strings.HasPrefix(hashed, "$5$"), strings.HasPrefix(hashed, "$6$"):
+		computed, err := shaCrypt(password, hashed)
+		if err != nil {
+			return false
+		}
+		return constantEquals(computed, hashed)
+	case strings.HasPrefix(hashed, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return constantEquals("{SHA}"+base64.StdEncoding.EncodeToString(sum[:]), hashed)
+	}
+
+	return false
+}
+
+func constantEquals(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
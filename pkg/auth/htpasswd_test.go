@@ -0,0 +1,112 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type HtpasswdSuite struct {
+	suite.Suite
+	tempDir string
+	file    string
+}
+
+func TestHtpasswdSuite(t *testing.T) {
+	suite.Run(t, new(HtpasswdSuite))
+}
+
+func (s *HtpasswdSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "mdsite-auth")
+	s.Require().NoError(err)
+	s.tempDir = dir
+	s.file = filepath.Join(dir, "htpasswd")
+}
+
+func (s *HtpasswdSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+func (s *HtpasswdSuite) writeFile(content string) {
+	s.Require().NoError(ioutil.WriteFile(s.file, []byte(content), 0600))
+
+	// Make sure the change is visible, even on coarse filesystem clocks
+	future := time.Now().Add(time.Minute)
+	s.Require().NoError(os.Chtimes(s.file, future, future))
+}
+
+func (s *HtpasswdSuite) TestShaCrypt_Vectors() {
+	h, err := shaCrypt("Hello world!", "$5$saltstring")
+	s.Require().NoError(err)
+	s.Equal("$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5", h)
+
+	h, err = shaCrypt("Hello world!", "$6$saltstring")
+	s.Require().NoError(err)
+	s.Equal("$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", h)
+
+	h, err = shaCrypt("Hello world!", "$5$rounds=10000$saltstringsaltstring")
+	s.Require().NoError(err)
+	s.Equal("$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA", h)
+}
+
+func (s *HtpasswdSuite) TestAuthenticate_Formats() {
+	bc, err := bcrypt.GenerateFromPassword([]byte("bcrypt-pass"), bcrypt.MinCost)
+	s.Require().NoError(err)
+
+	s.writeFile(fmt.Sprintf("# Test users\nalice:%s\nbob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\ncarol:$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1\ndave:$apr1$abc$def\n", bc))
+
+	h, err := LoadHtpasswd(s.file)
+	s.Require().NoError(err)
+
+	s.True(h.Authenticate("alice", "bcrypt-pass"))
+	s.False(h.Authenticate("alice", "wrong"))
+	s.True(h.Authenticate("bob", "password"))
+	s.False(h.Authenticate("bob", "Password"))
+	s.True(h.Authenticate("carol", "Hello world!"))
+	s.False(h.Authenticate("dave", "anything"))
+	s.False(h.Authenticate("nobody", "password"))
+}
+
+func (s *HtpasswdSuite) TestAuthenticate_Reload() {
+	s.writeFile("bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")
+
+	h, err := LoadHtpasswd(s.file)
+	s.Require().NoError(err)
+	s.True(h.Authenticate("bob", "password"))
+
+	s.Require().NoError(ioutil.WriteFile(s.file, []byte("eve:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0600))
+	past := time.Now().Add(-time.Hour)
+	s.Require().NoError(os.Chtimes(s.file, past, past))
+	h.Refresh()
+
+	s.False(h.Authenticate("bob", "password"))
+	s.True(h.Authenticate("eve", "password"))
+}
+
+func (s *HtpasswdSuite) TestLoad_Missing() {
+	h, err := LoadHtpasswd(filepath.Join(s.tempDir, "none"))
+
+	s.Error(err)
+	s.Nil(h)
+}
@@ -0,0 +1,458 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultUserClaim   = "sub"
+	DefaultGroupsClaim = "groups"
+)
+
+// JwtVerifier validates signed JSON Web Tokens against a fixed set of public
+// keys, and maps their claims onto a User.
+type JwtVerifier struct {
+	Issuer      string
+	Audience    string
+	UserClaim   string
+	GroupsClaim string
+	Leeway      time.Duration
+
+	keyed   map[string]crypto.PublicKey
+	keys    []crypto.PublicKey
+	nowFunc func() time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewJwtVerifier() *JwtVerifier {
+	return &JwtVerifier{
+		UserClaim:   DefaultUserClaim,
+		GroupsClaim: DefaultGroupsClaim,
+		keyed:       make(map[string]crypto.PublicKey),
+		nowFunc:     time.Now,
+	}
+}
+
+func (v *JwtVerifier) AddKey(kid string, key crypto.PublicKey) {
+	if kid != "" {
+		v.keyed[kid] = key
+	}
+	v.keys = append(v.keys, key)
+}
+
+func (v *JwtVerifier) KeyCount() int {
+	return len(v.keys)
+}
+
+// LoadJwks adds the signing keys from a JSON Web Key Set file.
+func (v *JwtVerifier) LoadJwks(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return fmt.Errorf("invalid JWKS file: %s", err)
+	}
+
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return fmt.Errorf("JWKS key %d (%s): %s", i, jwk.Kid, err)
+		}
+
+		v.AddKey(jwk.Kid, key)
+	}
+
+	return nil
+}
+
+// LoadPem adds every public key or certificate from a PEM file.
+func (v *JwtVerifier) LoadPem(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	found := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s in %s: %s", block.Type, path, err)
+		}
+
+		v.AddKey("", key)
+		found++
+	}
+
+	if found == 0 {
+		return fmt.Errorf("no public keys found in %s", path)
+	}
+
+	return nil
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := decodeSegment(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// Verify checks the signature and claims of a compact serialized token, and
+// returns the user it identifies.
+func (v *JwtVerifier) Verify(token string) (*User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	headerData, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	header := jwtHeader{}
+	err = json.Unmarshal(headerData, &header)
+	if err != nil {
+		return nil, errors.New("malformed token header")
+	}
+
+	sig, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+
+	err = v.verifySignature(header, []byte(parts[0]+"."+parts[1]), sig)
+	if err != nil {
+		return nil, err
+	}
+
+	claimData, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	claims := make(map[string]interface{})
+	err = json.Unmarshal(claimData, &claims)
+	if err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+
+	err = v.checkClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.mapUser(claims)
+}
+
+func (v *JwtVerifier) verifySignature(header jwtHeader, signed []byte, sig []byte) error {
+	candidates := v.keys
+	if key, ok := v.keyed[header.Kid]; ok {
+		candidates = []crypto.PublicKey{key}
+	}
+
+	for _, key := range candidates {
+		err := verifyWithKey(header.Alg, key, signed, sig)
+		if err == nil {
+			return nil
+		}
+		if err == errUnsupportedAlg {
+			return err
+		}
+	}
+
+	return errors.New("invalid token signature")
+}
+
+var errUnsupportedAlg = errors.New("unsupported signing algorithm")
+var errKeyMismatch = errors.New("key does not match algorithm")
+
+// The curve size each ECDSA algorithm is defined for
+var ecdsaCurveSizes = map[string]int{
+	"ES256": 256,
+	"ES384": 384,
+	"ES512": 521,
+}
+
+func verifyWithKey(alg string, key crypto.PublicKey, signed []byte, sig []byte) error {
+	var h crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		h = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		h = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		h = crypto.SHA512
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errKeyMismatch
+		}
+		if !ed25519.Verify(edKey, signed, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		// Notably, this rejects "none" and the shared secret HMAC algorithms
+		return errUnsupportedAlg
+	}
+
+	hasher := h.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch alg[0] {
+	case 'R':
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKeyMismatch
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, h, digest, sig)
+	case 'P':
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKeyMismatch
+		}
+		return rsa.VerifyPSS(rsaKey, h, digest, sig, nil)
+	default:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve.Params().BitSize != ecdsaCurveSizes[alg] {
+			return errKeyMismatch
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+}
+
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool, error) {
+	raw, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	n, ok := raw.(float64)
+	if !ok {
+		return time.Time{}, true, fmt.Errorf("invalid %s claim", name)
+	}
+
+	return time.Unix(int64(n), 0), true, nil
+}
+
+func (v *JwtVerifier) checkClaims(claims map[string]interface{}) error {
+	now := v.nowFunc()
+
+	exp, ok, err := numericClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(exp.Add(v.Leeway)) {
+		return errors.New("token has expired")
+	}
+
+	nbf, ok, err := numericClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.Leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+
+	if v.Issuer != "" {
+		iss, _ := claims["iss"].(string)
+		if iss != v.Issuer {
+			return fmt.Errorf("unexpected token issuer: %s", iss)
+		}
+	}
+
+	if v.Audience != "" && !containsString(stringList(claims["aud"]), v.Audience) {
+		return errors.New("token is not intended for this audience")
+	}
+
+	return nil
+}
+
+func (v *JwtVerifier) mapUser(claims map[string]interface{}) (*User, error) {
+	name, _ := claims[v.UserClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("token has no %s claim", v.UserClaim)
+	}
+
+	return &User{
+		Name:   name,
+		Groups: stringList(claims[v.GroupsClaim]),
+	}, nil
+}
+
+// stringList reads a claim which may be either a single string or a list.
+func stringList(raw interface{}) []string {
+	switch val := raw.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		list := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
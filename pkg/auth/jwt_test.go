@@ -0,0 +1,248 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type JwtSuite struct {
+	suite.Suite
+	tempDir string
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+}
+
+func TestJwtSuite(t *testing.T) {
+	suite.Run(t, new(JwtSuite))
+}
+
+func (s *JwtSuite) SetupSuite() {
+	var err error
+	s.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	s.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+}
+
+func (s *JwtSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "mdsite-jwt")
+	s.Require().NoError(err)
+	s.tempDir = dir
+}
+
+func (s *JwtSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signToken(alg string, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	body, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(body)
+
+	h := crypto.SHA256
+	switch {
+	case strings.HasSuffix(alg, "384"):
+		h = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		h = crypto.SHA512
+	}
+
+	digest := h.New()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, k, h, sum)
+	case *ecdsa.PrivateKey:
+		r, ss, _ := ecdsa.Sign(rand.Reader, k, sum)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		rb, sb := r.Bytes(), ss.Bytes()
+		copy(sig[size-len(rb):size], rb)
+		copy(sig[2*size-len(sb):], sb)
+	}
+
+	return signed + "." + b64(sig)
+}
+
+func (s *JwtSuite) writeJwks() string {
+	e := big64(s.rsaKey.PublicKey.E)
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(s.rsaKey.PublicKey.N.Bytes()), "e": b64(e)},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(s.ecKey.PublicKey.X.Bytes()), "y": b64(s.ecKey.PublicKey.Y.Bytes())},
+		},
+	}
+
+	data, err := json.Marshal(jwks)
+	s.Require().NoError(err)
+
+	path := filepath.Join(s.tempDir, "jwks.json")
+	s.Require().NoError(ioutil.WriteFile(path, data, 0600))
+
+	return path
+}
+
+func big64(e int) []byte {
+	return []byte{byte(e >> 16), byte(e >> 8), byte(e)}
+}
+
+func (s *JwtSuite) claims(extra map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub":    "alice",
+		"iss":    "https://sso.example.com",
+		"aud":    []string{"mdsite", "other"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"eng", "oncall"},
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+
+	return c
+}
+
+func (s *JwtSuite) verifier() *JwtVerifier {
+	v := NewJwtVerifier()
+	v.Issuer = "https://sso.example.com"
+	v.Audience = "mdsite"
+	s.Require().NoError(v.LoadJwks(s.writeJwks()))
+	s.Equal(2, v.KeyCount())
+
+	return v
+}
+
+func (s *JwtSuite) TestVerify_Rsa() {
+	u, err := s.verifier().Verify(signToken("RS256", "rsa-1", s.rsaKey, s.claims(nil)))
+
+	s.Require().NoError(err)
+	s.Equal("alice", u.Name)
+	s.Equal([]string{"eng", "oncall"}, u.Groups)
+	s.True(u.InGroup("oncall"))
+}
+
+func (s *JwtSuite) TestVerify_EcWithoutKid() {
+	u, err := s.verifier().Verify(signToken("ES256", "", s.ecKey, s.claims(nil)))
+
+	s.Require().NoError(err)
+	s.Equal("alice", u.Name)
+}
+
+func (s *JwtSuite) TestVerify_EcCurveMismatch() {
+	// Signed with the P-256 key, but claiming the P-384 algorithm
+	u, err := s.verifier().Verify(signToken("ES384", "ec-1", s.ecKey, s.claims(nil)))
+
+	s.Error(err)
+	s.Nil(u)
+}
+
+func (s *JwtSuite) TestVerifyWithKey_EcCurves() {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	s.Require().NoError(err)
+
+	parts := strings.Split(signToken("ES384", "", p384, s.claims(nil)), ".")
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	s.Require().NoError(err)
+	signed := []byte(parts[0] + "." + parts[1])
+
+	s.NoError(verifyWithKey("ES384", &p384.PublicKey, signed, sig))
+	s.Equal(errKeyMismatch, verifyWithKey("ES256", &p384.PublicKey, signed, sig))
+	s.Equal(errKeyMismatch, verifyWithKey("ES384", &s.ecKey.PublicKey, signed, sig))
+}
+
+func (s *JwtSuite) TestVerify_ClaimMapping() {
+	v := s.verifier()
+	v.UserClaim = "email"
+	v.GroupsClaim = "roles"
+
+	u, err := v.Verify(signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{
+		"email": "alice@example.com",
+		"roles": "admin",
+	})))
+
+	s.Require().NoError(err)
+	s.Equal("alice@example.com", u.Name)
+	s.Equal([]string{"admin"}, u.Groups)
+}
+
+func (s *JwtSuite) TestVerify_Rejections() {
+	v := s.verifier()
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	cases := map[string]string{
+		"expired":    signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiry":  signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{"exp": nil})),
+		"not before": signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"issuer":     signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"audience":   signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{"aud": "elsewhere"})),
+		"no subject": signToken("RS256", "rsa-1", s.rsaKey, s.claims(map[string]interface{}{"sub": ""})),
+		"wrong key":  signToken("RS256", "rsa-1", other, s.claims(nil)),
+		"alg none":   signToken("none", "", s.rsaKey, s.claims(nil)),
+		"malformed":  "not.a-token",
+	}
+
+	for name, token := range cases {
+		u, err := v.Verify(token)
+		s.Error(err, name)
+		s.Nil(u, name)
+	}
+}
+
+func (s *JwtSuite) TestLoadPem() {
+	der, err := x509.MarshalPKIXPublicKey(&s.rsaKey.PublicKey)
+	s.Require().NoError(err)
+
+	path := filepath.Join(s.tempDir, "key.pem")
+	s.Require().NoError(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	v := NewJwtVerifier()
+	s.Require().NoError(v.LoadPem(path))
+
+	u, err := v.Verify(signToken("RS256", "unknown", s.rsaKey, s.claims(nil)))
+	s.Require().NoError(err)
+	s.Equal("alice", u.Name)
+}
+
+func (s *JwtSuite) TestLoadPem_Empty() {
+	path := filepath.Join(s.tempDir, "empty.pem")
+	s.Require().NoError(ioutil.WriteFile(path, []byte("nothing here\n"), 0600))
+
+	s.Error(NewJwtVerifier().LoadPem(path))
+}
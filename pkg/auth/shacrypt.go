@@ -0,0 +1,206 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// An implementation of the SHA-256 and SHA-512 based crypt(3) schemes
+// ($5$ and $6$), as described in https://www.akkadia.org/drepper/SHA-crypt.txt
+
+const (
+	shaCryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shaCryptDefaultRounds = 5000
+	shaCryptMinRounds     = 1000
+	shaCryptMaxRounds     = 999999999
+	shaCryptMaxSalt       = 16
+)
+
+var sha256Order = [][3]int{
+	{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+	{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+}
+
+var sha512Order = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+	{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+	{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+	{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+	{62, 20, 41},
+}
+
+// shaCrypt hashes a password using the settings (scheme, rounds and salt)
+// from an existing crypt string.
+func shaCrypt(password string, settings string) (string, error) {
+	var newHash func() hash.Hash
+	var prefix string
+
+	switch {
+	case strings.HasPrefix(settings, "$5$"):
+		newHash, prefix = sha256.New, "$5$"
+	case strings.HasPrefix(settings, "$6$"):
+		newHash, prefix = sha512.New, "$6$"
+	default:
+		return "", errors.New("unsupported crypt scheme")
+	}
+
+	rest := strings.TrimPrefix(settings, prefix)
+	rounds := shaCryptDefaultRounds
+	customRounds := false
+
+	if strings.HasPrefix(rest, "rounds=") {
+		end := strings.IndexByte(rest, '$')
+		if end < 0 {
+			return "", errors.New("malformed rounds")
+		}
+
+		r, err := strconv.Atoi(strings.TrimPrefix(rest[:end], "rounds="))
+		if err != nil {
+			return "", errors.New("malformed rounds")
+		}
+		if r < shaCryptMinRounds {
+			r = shaCryptMinRounds
+		} else if r > shaCryptMaxRounds {
+			r = shaCryptMaxRounds
+		}
+
+		rounds = r
+		customRounds = true
+		rest = rest[end+1:]
+	}
+
+	salt := rest
+	if i := strings.IndexByte(salt, '$'); i >= 0 {
+		salt = salt[:i]
+	}
+	if len(salt) > shaCryptMaxSalt {
+		salt = salt[:shaCryptMaxSalt]
+	}
+
+	digest := shaCryptDigest(newHash, []byte(password), []byte(salt), rounds)
+
+	out := strings.Builder{}
+	out.WriteString(prefix)
+	if customRounds {
+		out.WriteString("rounds=")
+		out.WriteString(strconv.Itoa(rounds))
+		out.WriteByte('$')
+	}
+	out.WriteString(salt)
+	out.WriteByte('$')
+
+	if prefix == "$5$" {
+		for _, o := range sha256Order {
+			encode24(&out, digest[o[0]], digest[o[1]], digest[o[2]], 4)
+		}
+		encode24(&out, 0, digest[31], digest[30], 3)
+	} else {
+		for _, o := range sha512Order {
+			encode24(&out, digest[o[0]], digest[o[1]], digest[o[2]], 4)
+		}
+		encode24(&out, 0, 0, digest[63], 2)
+	}
+
+	return out.String(), nil
+}
+
+func shaCryptDigest(newHash func() hash.Hash, password []byte, salt []byte, rounds int) []byte {
+	h := newHash()
+	size := h.Size()
+
+	// Digest B
+	h.Write(password)
+	h.Write(salt)
+	h.Write(password)
+	b := h.Sum(nil)
+
+	// Digest A
+	h.Reset()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(repeatBytes(b, len(password)))
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write(b)
+		} else {
+			h.Write(password)
+		}
+	}
+	a := h.Sum(nil)
+
+	// Byte sequence P
+	h.Reset()
+	for i := 0; i < len(password); i++ {
+		h.Write(password)
+	}
+	p := repeatBytes(h.Sum(nil), len(password))
+
+	// Byte sequence S
+	h.Reset()
+	for i := 0; i < 16+int(a[0]); i++ {
+		h.Write(salt)
+	}
+	s := repeatBytes(h.Sum(nil), len(salt))
+
+	c := a
+	for i := 0; i < rounds; i++ {
+		h.Reset()
+		if i%2 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i%2 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(nil)
+	}
+
+	return c[:size]
+}
+
+func repeatBytes(src []byte, length int) []byte {
+	buf := bytes.Buffer{}
+	for buf.Len() < length {
+		buf.Write(src)
+	}
+
+	return buf.Bytes()[:length]
+}
+
+func encode24(out *strings.Builder, b2 byte, b1 byte, b0 byte, n int) {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for i := 0; i < n; i++ {
+		out.WriteByte(shaCryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/util"
+	"path/filepath"
+	"time"
+)
+
+// SiteAuth is how a single site authenticates users, and the rules for what
+// they can read. Any part that is not configured is nil.
+type SiteAuth struct {
+	Htpasswd *Htpasswd
+	Jwt      *JwtVerifier
+	Acl      *AccessList
+}
+
+// LoadSiteAuth reads the password, key and access rule files named in the
+// auth settings of a site.
+func LoadSiteAuth(v *config.Values) (*SiteAuth, error) {
+	ac := v.SiteConfig.Auth
+	sa := SiteAuth{}
+
+	acl, err := loadAccessList(v, ac)
+	if err != nil {
+		return nil, err
+	}
+	sa.Acl = acl
+
+	if !ac.Enabled() {
+		return &sa, nil
+	}
+
+	if ac.Htpasswd != "" {
+		h, err := LoadHtpasswd(configFile(v, ac.Htpasswd))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load htpasswd file: %s", err)
+		}
+		sa.Htpasswd = h
+	}
+
+	if ac.Jwt.Enabled() {
+		j, err := loadJwtVerifier(v, ac.Jwt)
+		if err != nil {
+			return nil, err
+		}
+		sa.Jwt = j
+	}
+
+	return &sa, nil
+}
+
+// loadAccessList reads the access rules for the site. The default rule file
+// is optional, but a file named explicitly in the site config must exist.
+func loadAccessList(v *config.Values, ac config.AuthConfig) (*AccessList, error) {
+	if ac.Acl == "" {
+		return nil, nil
+	}
+
+	file := configFile(v, ac.Acl)
+	if ac.Acl == config.DefaultAclFile && !util.FileExists(file) {
+		return nil, nil
+	}
+
+	acl, err := LoadAccessList(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access rules: %s", err)
+	}
+
+	if !ac.Enabled() {
+		log.Warnf("Access rules are configured without authentication; restricted pages will not be readable")
+	}
+
+	return acl, nil
+}
+
+func configFile(v *config.Values, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+
+	return filepath.Join(v.ConfigPath, file)
+}
+
+func loadJwtVerifier(v *config.Values, jc config.JwtConfig) (*JwtVerifier, error) {
+	j := NewJwtVerifier()
+	j.Issuer = jc.Issuer
+	j.Audience = jc.Audience
+	j.Leeway = time.Duration(jc.Leeway) * time.Second
+	if jc.UserClaim != "" {
+		j.UserClaim = jc.UserClaim
+	}
+	if jc.GroupsClaim != "" {
+		j.GroupsClaim = jc.GroupsClaim
+	}
+
+	if jc.Jwks != "" {
+		err := j.LoadJwks(configFile(v, jc.Jwks))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWKS file: %s", err)
+		}
+	}
+
+	for _, keyFile := range jc.Keys {
+		err := j.LoadPem(configFile(v, keyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key: %s", err)
+		}
+	}
+
+	if j.KeyCount() == 0 {
+		return nil, errors.New("no JWT signing keys configured")
+	}
+
+	return j, nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const contextUser = "mdsite-user"
+
+type User struct {
+	Name   string
+	Groups []string
+}
+
+func (u *User) InGroup(group string) bool {
+	if u == nil {
+		return false
+	}
+
+	for _, g := range u.Groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+func SetContextUser(c *gin.Context, u *User) {
+	c.Set(contextUser, u)
+}
+
+// ContextUser returns the authenticated user for the request, or nil for an
+// anonymous request.
+func ContextUser(c *gin.Context) *User {
+	u, _ := c.Value(contextUser).(*User)
+
+	return u
+}
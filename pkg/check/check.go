@@ -0,0 +1,290 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package check
+
+import (
+	"fmt"
+	"github.com/zpxio/mdsite/pkg/auth"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/resource"
+	"github.com/zpxio/mdsite/pkg/site"
+	"html/template"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Report is the machine readable outcome of checking one or more sites. It
+// is only Ok if no errors were found.
+type Report struct {
+	Ok       bool         `json:"ok"`
+	Problems []Problem    `json:"problems,omitempty"`
+	Sites    []SiteReport `json:"sites"`
+}
+
+type SiteReport struct {
+	Name       string    `json:"name"`
+	SitePath   string    `json:"sitePath"`
+	ConfigPath string    `json:"configPath"`
+	Pages      int       `json:"pages"`
+	Problems   []Problem `json:"problems"`
+}
+
+type Problem struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Run checks every site that the server settings would serve.
+func Run(v *config.Values) *Report {
+	r := Report{Sites: []SiteReport{}}
+
+	if v.SitesFile != "" {
+		sf, err := config.LoadSitesFile(v.SitesFile)
+		if err != nil {
+			r.Problems = append(r.Problems, Problem{
+				Check:    "sites",
+				Severity: SeverityError,
+				File:     v.SitesFile,
+				Message:  err.Error(),
+			})
+		} else {
+			for _, vs := range sf.Sites {
+				r.Sites = append(r.Sites, CheckSite(vs.Name, vs.Values(v)))
+			}
+		}
+	} else {
+		r.Sites = append(r.Sites, CheckSite("default", v))
+	}
+
+	r.Ok = !hasErrors(r.Problems)
+	for _, sr := range r.Sites {
+		r.Ok = r.Ok && !hasErrors(sr.Problems)
+	}
+
+	return &r
+}
+
+func hasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *SiteReport) add(check string, severity string, file string, message string) {
+	r.Problems = append(r.Problems, Problem{
+		Check:    check,
+		Severity: severity,
+		File:     file,
+		Message:  message,
+	})
+}
+
+// CheckSite validates the config, templates, index, page order and internal
+// links of a site.
+func CheckSite(name string, base *config.Values) SiteReport {
+	v := *base
+
+	r := SiteReport{
+		Name:       name,
+		SitePath:   v.SitePath,
+		ConfigPath: v.ConfigPath,
+		Problems:   []Problem{},
+	}
+	siteFile := filepath.Join(v.ConfigPath, "site.yml")
+
+	// Load strictly first, then leniently to find any further problems
+	sc, err := config.LoadSiteConfigStrict(&v)
+	if _, isTemplate := err.(config.TemplateErrors); err != nil && !isTemplate {
+		r.add("config", SeverityError, siteFile, err.Error())
+
+		sc, err = config.LoadSiteConfig(&v)
+		if _, isTemplate := err.(config.TemplateErrors); err != nil && !isTemplate {
+			return r
+		}
+	}
+	if errs, ok := err.(config.TemplateErrors); ok {
+		for _, te := range errs {
+			r.add("template", SeverityError, siteFile, te.Error())
+		}
+	}
+	v.SiteConfig = sc
+
+	_, err = auth.LoadSiteAuth(&v)
+	if err != nil {
+		r.add("config", SeverityError, siteFile, err.Error())
+	}
+
+	// Render the pages as the server would, so that links are checked
+	indexer := site.NewIndexer(&v)
+	indexer.Scan = resource.NewPages(&v, resource.DefaultRenderers()).ScanLinks
+	index, err := indexer.ReIndex()
+	if err != nil {
+		r.add("index", SeverityError, v.SitePath, err.Error())
+		return r
+	}
+	r.Pages = len(index.Pages)
+	if r.Pages == 0 {
+		r.add("index", SeverityWarning, v.SitePath, "the site has no pages")
+	}
+
+	r.checkOrder(&v, index)
+	r.checkLinks(&v, index)
+	r.checkTemplates(&v, index)
+
+	return r
+}
+
+func (r *SiteReport) checkLinks(v *config.Values, index *site.PageIndex) {
+	for _, p := range index.Pages {
+		for _, l := range p.BrokenLinks() {
+			r.add("links", SeverityError, filepath.Join(v.SitePath, p.Path),
+				fmt.Sprintf("broken %s: %s", l.Kind, l.Href))
+		}
+		for _, name := range p.AmbiguousLinks {
+			r.add("links", SeverityWarning, filepath.Join(v.SitePath, p.Path),
+				fmt.Sprintf("ambiguous wiki link: %s", name))
+		}
+	}
+}
+
+func (r *SiteReport) checkOrder(v *config.Values, index *site.PageIndex) {
+	orderFile := filepath.Join(v.ConfigPath, site.OrderFile)
+
+	order, err := site.ReadOrder(v.ConfigPath, true)
+	if err != nil {
+		r.add("order", SeverityError, orderFile, err.Error())
+		return
+	}
+	if order == nil {
+		return
+	}
+
+	pages := make(map[string]bool)
+	for _, p := range index.Pages {
+		pages[filepath.ToSlash(p.Path)] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range order.Order {
+		if seen[entry] {
+			r.add("order", SeverityWarning, orderFile, fmt.Sprintf("duplicate entry: %s", entry))
+		}
+		seen[entry] = true
+
+		if !pages[entry] {
+			r.add("order", SeverityError, orderFile, fmt.Sprintf("entry does not match a page: %s", entry))
+		}
+	}
+}
+
+// checkTemplates executes every template against sample data, to find
+// errors that only show up when a template is used.
+func (r *SiteReport) checkTemplates(v *config.Values, index *site.PageIndex) {
+	templates := v.SiteConfig.Templates()
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := templates[name]
+		if !t.Resolved() {
+			continue
+		}
+
+		err := t.Execute(ioutil.Discard, sampleData(name, v, index))
+		if err != nil {
+			r.add("template", SeverityError, filepath.Join(v.ConfigPath, "site.yml"),
+				fmt.Sprintf("template %s failed a dry run: %s", name, err))
+		}
+	}
+}
+
+// sampleData gives each template the kind of data it is executed with.
+func sampleData(name string, v *config.Values, index *site.PageIndex) interface{} {
+	content := template.HTML("<p>Sample content</p>")
+
+	switch name {
+	case "global.pageTemplate":
+		return &resource.RenderData{
+			Resource: "sample.md",
+			Title:    v.SiteConfig.Title,
+			BaseUrl:  v.BaseUrl(),
+			User:     &auth.User{Name: "sample"},
+			Content:  content,
+			Page: &site.PageEntry{
+				Path:     "sample.md",
+				Url:      v.SiteUrl("/sample"),
+				Label:    "Sample",
+				Modified: time.Now(),
+				Commit:   &site.GitCommit{Hash: "0000000000000000000000000000000000000000", Author: "sample", Date: time.Now(), AuthorDate: time.Now()},
+			},
+			Breadcrumbs: []site.Crumb{
+				{Label: v.SiteConfig.Title, Url: v.SiteUrl("/")},
+			},
+			Outline: []*resource.Heading{
+				{Level: 2, Text: "Sample", Id: "sample"},
+			},
+		}
+	case "global.tocTemplate", "toc.pageTemplate":
+		return index
+	case "global.taxonomyTemplate":
+		return sampleTaxonomy(index, false)
+	case "global.termTemplate":
+		return sampleTaxonomy(index, true)
+	}
+
+	if strings.HasPrefix(name, "taxonomies.") {
+		return sampleTaxonomy(index, strings.HasSuffix(name, ".termTemplate"))
+	}
+
+	return content
+}
+
+func sampleTaxonomy(index *site.PageIndex, withTerm bool) site.TaxonomyPage {
+	term := &site.Term{Name: "Sample", Slug: "sample", Url: "/tags/sample", Pages: index.Pages}
+	t := &site.Taxonomy{
+		Name:       "tags",
+		Title:      "Tags",
+		Url:        "/tags/",
+		Terms:      []*site.Term{term},
+		TermLookup: map[string]*site.Term{term.Slug: term},
+	}
+
+	data := site.TaxonomyPage{Taxonomy: t}
+	if withTerm {
+		data.Term = term
+	}
+
+	return data
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package check
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type CheckTestSuite struct {
+	suite.Suite
+	sitesPath string
+}
+
+func TestCheckTestSuite(t *testing.T) {
+	suite.Run(t, new(CheckTestSuite))
+}
+
+func (s *CheckTestSuite) SetupTest() {
+	cwd, err := os.Getwd()
+	s.Require().NoError(err)
+
+	s.sitesPath = filepath.Join(filepath.Dir(filepath.Dir(cwd)), "testdata/sites")
+}
+
+func (s *CheckTestSuite) values(siteName string) *config.Values {
+	v := config.Create()
+	v.ConfigPath = filepath.Join(s.sitesPath, siteName, "config")
+	v.SitePath = filepath.Join(s.sitesPath, siteName, "site")
+
+	return v
+}
+
+func (s *CheckTestSuite) problems(r SiteReport, check string, severity string) []string {
+	var msgs []string
+	for _, p := range r.Problems {
+		if p.Check == check && p.Severity == severity {
+			msgs = append(msgs, p.Message)
+		}
+	}
+
+	return msgs
+}
+
+func (s *CheckTestSuite) TestCheck_Clean() {
+	r := Run(s.values("test01"))
+
+	s.True(r.Ok)
+	s.Require().Len(r.Sites, 1)
+	s.Equal(4, r.Sites[0].Pages)
+	s.Empty(r.Sites[0].Problems)
+}
+
+func (s *CheckTestSuite) TestCheck_Problems() {
+	r := Run(s.values("check01"))
+
+	s.False(r.Ok)
+	s.Require().Len(r.Sites, 1)
+	sr := r.Sites[0]
+	s.Equal(2, sr.Pages)
+
+	config := s.problems(sr, "config", SeverityError)
+	s.Require().Len(config, 1)
+	s.Contains(config[0], "pageTemplate")
+
+	templates := s.problems(sr, "template", SeverityError)
+	s.Require().Len(templates, 1)
+	s.Contains(templates[0], "global.pageTemplate")
+	s.Contains(templates[0], "Missing")
+
+	s.Equal([]string{"entry does not match a page: moved.md"}, s.problems(sr, "order", SeverityError))
+	s.Equal([]string{"duplicate entry: page.md"}, s.problems(sr, "order", SeverityWarning))
+}
+
+func (s *CheckTestSuite) TestCheck_BadOrderFile() {
+	r := Run(s.values("fail04"))
+
+	s.False(r.Ok)
+	s.Len(s.problems(r.Sites[0], "order", SeverityError), 1)
+}
+
+func (s *CheckTestSuite) TestCheck_TemplateErrors() {
+	r := Run(s.values("fail01"))
+
+	s.False(r.Ok)
+	templates := s.problems(r.Sites[0], "template", SeverityError)
+	s.Require().Len(templates, 1)
+	s.Contains(templates[0], "html.blockTemplate")
+}
+
+func (s *CheckTestSuite) TestCheck_MissingConfig() {
+	r := Run(s.values("fail02"))
+
+	s.False(r.Ok)
+	s.Len(s.problems(r.Sites[0], "config", SeverityError), 1)
+	s.Equal(0, r.Sites[0].Pages)
+}
+
+func (s *CheckTestSuite) TestCheck_BadAuth() {
+	r := Run(s.values("fail09"))
+
+	s.False(r.Ok)
+	config := s.problems(r.Sites[0], "config", SeverityError)
+	s.Require().Len(config, 1)
+	s.Contains(config[0], "htpasswd")
+	s.Equal(1, r.Sites[0].Pages)
+}
+
+func (s *CheckTestSuite) TestCheck_SitesFile() {
+	v := config.Create()
+	v.SitesFile = filepath.Join(s.sitesPath, "hosts.yml")
+
+	r := Run(v)
+	s.Require().Len(r.Sites, 2)
+	s.Equal("primary", r.Sites[0].Name)
+	s.Empty(r.Sites[0].Problems)
+}
+
+func (s *CheckTestSuite) TestCheck_SampleSite() {
+	v := config.Create()
+	v.ConfigPath = filepath.Join(filepath.Dir(s.sitesPath), "..", "sample", "config")
+	v.SitePath = filepath.Join(filepath.Dir(s.sitesPath), "..", "sample", "site")
+
+	r := Run(v)
+	s.True(r.Ok, "%+v", r.Sites)
+}
+
+func (s *CheckTestSuite) TestCheck_BrokenLinks() {
+	r := Run(s.values("links01"))
+
+	s.False(r.Ok)
+	links := s.problems(r.Sites[0], "links", SeverityError)
+	s.Len(links, 3)
+}
+
+func (s *CheckTestSuite) TestCheck_AmbiguousWikiLinks() {
+	r := Run(s.values("wiki01"))
+
+	s.True(r.Ok)
+	s.Equal([]string{"ambiguous wiki link: index"}, s.problems(r.Sites[0], "links", SeverityWarning))
+}
+
+func (s *CheckTestSuite) TestCheck_Taxonomies() {
+	r := Run(s.values("taxonomy01"))
+
+	s.True(r.Ok, "%+v", r.Sites)
+	s.Empty(r.Sites[0].Problems)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const WildcardHost = "*"
+
+// SitesFile declares several sites served by one process, each selected by
+// the Host header of the request.
+type SitesFile struct {
+	Sites []VirtualSite `yaml:"sites"`
+}
+
+type VirtualSite struct {
+	Name       string   `yaml:"name"`
+	Hosts      []string `yaml:"hosts"`
+	SitePath   string   `yaml:"site"`
+	ConfigPath string   `yaml:"config"`
+	BasePath   string   `yaml:"basePath"`
+}
+
+func LoadSitesFile(path string) (*SitesFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Loading sites from: %s", path)
+
+	sf := SitesFile{}
+	err = yaml.Unmarshal(data, &sf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sf.Sites) == 0 {
+		return nil, fmt.Errorf("no sites declared in %s", path)
+	}
+
+	// Paths are relative to the sites file
+	dir := filepath.Dir(path)
+	for i := range sf.Sites {
+		vs := &sf.Sites[i]
+
+		if vs.SitePath == "" || vs.ConfigPath == "" {
+			return nil, fmt.Errorf("site %d (%s) requires both a site and a config path", i, vs.Name)
+		}
+		if len(vs.Hosts) == 0 {
+			return nil, fmt.Errorf("site %d (%s) does not declare any hosts", i, vs.Name)
+		}
+
+		if !filepath.IsAbs(vs.SitePath) {
+			vs.SitePath = filepath.Join(dir, vs.SitePath)
+		}
+		if !filepath.IsAbs(vs.ConfigPath) {
+			vs.ConfigPath = filepath.Join(dir, vs.ConfigPath)
+		}
+		if vs.Name == "" {
+			vs.Name = vs.Hosts[0]
+		}
+		for h := range vs.Hosts {
+			vs.Hosts[h] = strings.ToLower(vs.Hosts[h])
+		}
+	}
+
+	return &sf, nil
+}
+
+// Values creates the settings for a virtual site, inheriting everything not
+// declared by the site from the server settings.
+func (vs VirtualSite) Values(base *Values) *Values {
+	v := *base
+	v.SitePath = vs.SitePath
+	v.ConfigPath = vs.ConfigPath
+	if vs.BasePath != "" {
+		v.BasePath = vs.BasePath
+	}
+	v.SiteConfig = Site{}
+
+	return &v
+}
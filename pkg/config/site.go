@@ -17,6 +17,7 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/apex/log"
@@ -27,20 +28,140 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
 type Site struct {
 	Title    string               `yaml:"title"`
+	BaseUrl  string               `yaml:"baseUrl"`
 	Global   GlobalRenderConfig   `yaml:"global"`
 	Markdown MarkdownRenderConfig `yaml:"markdown"`
 	Html     HtmlRenderConfig     `yaml:"html"`
 	Contents ContentsRenderConfig `yaml:"toc"`
+	Auth     AuthConfig           `yaml:"auth"`
+	Feed     FeedConfig           `yaml:"feed"`
+	Sitemap  SitemapConfig        `yaml:"sitemap"`
+	Git      GitConfig            `yaml:"git"`
+	// The contents of robots.txt
+	Robots string `yaml:"robots"`
+
+	Taxonomies []TaxonomyConfig `yaml:"taxonomies"`
+}
+
+// FeedConfig controls the feeds of recently changed pages.
+type FeedConfig struct {
+	// The number of pages in each feed
+	Size int `yaml:"size"`
+	// The top level sections included in the feeds, or every section if
+	// none are given. Pages at the top of the site are in the "/" section.
+	Sections []string `yaml:"sections"`
+	// Include the rendered page in each entry, and not only a summary
+	Content bool `yaml:"content"`
+}
+
+// GitConfig controls reading page history from the git repository holding
+// the site, if there is one.
+type GitConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// The most URLs a single sitemap file may list
+const MaxSitemapSize = 50000
+
+// SitemapConfig controls the sitemap of the site.
+type SitemapConfig struct {
+	// The most pages listed in one sitemap file. Larger sites are split into
+	// several files, listed by a sitemap index.
+	Size int `yaml:"size"`
+}
+
+// Includes checks whether pages of a top level section are part of the
+// feeds.
+func (f FeedConfig) Includes(section string) bool {
+	if len(f.Sections) == 0 {
+		return true
+	}
+
+	for _, s := range f.Sections {
+		if s == section {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TaxonomyConfig declares a way of grouping pages, such as tags, by the terms
+// given for it in the front matter of each page.
+type TaxonomyConfig struct {
+	// The front matter key holding the terms
+	Name string `yaml:"name"`
+	// The route of the taxonomy pages, which defaults to the name
+	Path  string `yaml:"path"`
+	Title string `yaml:"title"`
+
+	// Override the global taxonomy templates
+	ListTemplate *RenderTemplate `yaml:"listTemplate"`
+	TermTemplate *RenderTemplate `yaml:"termTemplate"`
+}
+
+// Routes used by the site and the server, which taxonomies cannot use
+var reservedPaths = map[string]bool{
+	"api":         true,
+	"toc":         true,
+	"feeds":       true,
+	"feed.atom":   true,
+	"feed.rss":    true,
+	"feed.json":   true,
+	"sitemap":     true,
+	"sitemap.xml": true,
+	"robots.txt":  true,
+	"_mdsite":     true,
+	"ping":        true,
+	"metrics":     true,
+	"healthz":     true,
+	"readyz":      true,
+}
+
+var taxonomyPath = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type AuthConfig struct {
+	Realm    string    `yaml:"realm"`
+	Htpasswd string    `yaml:"htpasswd"`
+	Jwt      JwtConfig `yaml:"jwt"`
+	Acl      string    `yaml:"acl"`
+	Exempt   []string  `yaml:"exempt"`
+}
+
+const DefaultAclFile = "acl.yml"
+
+type JwtConfig struct {
+	Jwks        string   `yaml:"jwks"`
+	Keys        []string `yaml:"keys"`
+	Issuer      string   `yaml:"issuer"`
+	Audience    string   `yaml:"audience"`
+	Cookie      string   `yaml:"cookie"`
+	UserClaim   string   `yaml:"userClaim"`
+	GroupsClaim string   `yaml:"groupsClaim"`
+	Leeway      int      `yaml:"leeway"`
+}
+
+// Enabled reports whether any authentication method is configured.
+func (a AuthConfig) Enabled() bool {
+	return a.Htpasswd != "" || a.Jwt.Enabled()
+}
+
+func (j JwtConfig) Enabled() bool {
+	return j.Jwks != "" || len(j.Keys) > 0
 }
 
 type GlobalRenderConfig struct {
-	PageTemplate *RenderTemplate `yaml:"pageTemplate"`
-	TocTemplate  *RenderTemplate `yaml:"tocTemplate"`
+	PageTemplate     *RenderTemplate `yaml:"pageTemplate"`
+	TocTemplate      *RenderTemplate `yaml:"tocTemplate"`
+	TaxonomyTemplate *RenderTemplate `yaml:"taxonomyTemplate"`
+	TermTemplate     *RenderTemplate `yaml:"termTemplate"`
 }
 
 type HtmlRenderConfig struct {
@@ -49,6 +170,18 @@ type HtmlRenderConfig struct {
 
 type MarkdownRenderConfig struct {
 	BlockTemplate *RenderTemplate `yaml:"blockTemplate"`
+	Outline       OutlineConfig   `yaml:"outline"`
+}
+
+// OutlineConfig selects the heading levels included in page outlines.
+type OutlineConfig struct {
+	MinLevel int `yaml:"minLevel"`
+	MaxLevel int `yaml:"maxLevel"`
+}
+
+// Includes checks whether headings of a level are part of the outline.
+func (o OutlineConfig) Includes(level int) bool {
+	return level >= o.MinLevel && level <= o.MaxLevel
 }
 
 type ContentsRenderConfig struct {
@@ -56,26 +189,23 @@ type ContentsRenderConfig struct {
 }
 
 type RenderTemplate struct {
-	tpl *template.Template
+	source string
+	tpl    *template.Template
 }
 
-var templateSerial = 0
-
-func nextTemplateName() string {
-	templateSerial++
-	return fmt.Sprintf("template-%05d", templateSerial)
+// TemplateLoader resolves template references for a single site. Relative
+// paths are resolved against the site's configuration directory, and helper
+// functions are bound to the site rather than to the process.
+type TemplateLoader struct {
+	ConfigPath string
+	Funcs      template.FuncMap
 }
 
-func createRenderTemplate(name string, tpl string) *RenderTemplate {
-	rt := RenderTemplate{}
-
-	pt, err := resolveTemplate(name, tpl)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to resolve template [%s]: %s", name, err))
+func NewTemplateLoader(configPath string) *TemplateLoader {
+	return &TemplateLoader{
+		ConfigPath: configPath,
+		Funcs:      templateFuncs(),
 	}
-	rt.tpl = pt
-
-	return &rt
 }
 
 func (t *RenderTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -85,7 +215,19 @@ func (t *RenderTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error
 		return err
 	}
 
-	rt, err := resolveTemplate(nextTemplateName(), tmplSt)
+	// Templates are resolved once the whole site config is known
+	t.source = tmplSt
+	t.tpl = nil
+
+	return nil
+}
+
+func (t *RenderTemplate) Resolve(l *TemplateLoader, name string) error {
+	if t.tpl != nil {
+		return nil
+	}
+
+	rt, err := l.Resolve(name, t.source)
 	if err != nil {
 		return err
 	}
@@ -95,15 +237,44 @@ func (t *RenderTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
+func (t *RenderTemplate) Resolved() bool {
+	return t.tpl != nil
+}
+
 func (t *RenderTemplate) Execute(w io.Writer, data interface{}) error {
+	if t.tpl == nil {
+		return errors.New("template has not been resolved")
+	}
+
 	return t.tpl.Execute(w, data)
 }
 
+func (t RenderTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
 func (t RenderTemplate) String() string {
+	if t.tpl == nil {
+		return "Template[unresolved]"
+	}
+
 	return fmt.Sprintf("Template[%s]", t.tpl.Name())
 }
 
-func resolveTemplate(name string, tmpl string) (*template.Template, error) {
+func (l *TemplateLoader) Resolve(name string, tmpl string) (*template.Template, error) {
+	t, err := l.parse(name, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Funcs != nil {
+		t.Funcs(l.Funcs)
+	}
+
+	return t, nil
+}
+
+func (l *TemplateLoader) parse(name string, tmpl string) (*template.Template, error) {
 	// Try an absolute path
 	if filepath.IsAbs(tmpl) {
 		// Load absolute file path
@@ -111,7 +282,7 @@ func resolveTemplate(name string, tmpl string) (*template.Template, error) {
 	}
 
 	// Try a relative path
-	path := filepath.Join(Global().ConfigPath, tmpl)
+	path := filepath.Join(l.ConfigPath, tmpl)
 	if _, err := os.Stat(path); err == nil {
 		// Load the relative path
 		return loadTemplateFile(name, path)
@@ -120,7 +291,7 @@ func resolveTemplate(name string, tmpl string) (*template.Template, error) {
 	// See if the string looks like a template
 	if strings.Contains(tmpl, "{{") {
 		// Load the template as a string
-		t, err := loadTemplate("", tmpl)
+		t, err := loadTemplate(name, tmpl)
 		if err != nil {
 			return nil, err
 		}
@@ -139,8 +310,19 @@ func loadTemplateFile(name string, tmplPath string) (*template.Template, error)
 	return loadTemplate(name, string(templateData))
 }
 
+// templateFuncs supplies the helper functions available to every template.
+// These defaults assume a site served from the root path, and are replaced
+// by site specific versions when a site config is loaded.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"url": func(p string) string {
+			return JoinUrl("", p)
+		},
+	}
+}
+
 func loadTemplate(name string, tmpl string) (*template.Template, error) {
-	t := template.New(name)
+	t := template.New(name).Funcs(templateFuncs())
 	_, err := t.Parse(tmpl)
 	if err != nil {
 		log.Errorf("Could not parse template [%s]: %s", name, err)
@@ -161,32 +343,247 @@ func initTemplate(name string, tmpl string) *template.Template {
 	return t
 }
 
+func defaultTemplate(tmpl string) *RenderTemplate {
+	return &RenderTemplate{source: tmpl}
+}
+
 func defaultSiteConfig() Site {
 	s := Site{
 		Title: "Default",
+		Global: GlobalRenderConfig{
+			PageTemplate:     defaultTemplate(`<!DOCTYPE html><html><head><title>{{.Title}}</title></head><body>{{.Content}}</body></html>`),
+			TocTemplate:      defaultTemplate(`<ul class="page-list">{{range .Pages}}<li class="toc-item" id="toc-{{.Id}}"><a href="{{.Url}}">{{.Label}}</a></li>{{end}}</ul>`),
+			TaxonomyTemplate: defaultTemplate(`<h1>{{.Taxonomy.Title}}</h1><ul class="taxonomy">{{range .Taxonomy.Terms}}<li><a href="{{.Url}}">{{.Name}}</a> ({{len .Pages}})</li>{{end}}</ul>`),
+			TermTemplate:     defaultTemplate(`<h1>{{.Taxonomy.Title}}: {{.Term.Name}}</h1><ul class="page-list">{{range .Term.Pages}}<li><a href="{{.Url}}">{{.Label}}</a></li>{{end}}</ul>`),
+		},
 		Markdown: MarkdownRenderConfig{
-			BlockTemplate: createRenderTemplate("md-default", `<div id="content markdown">{{.}}</div>"`),
+			BlockTemplate: defaultTemplate(`<div id="content markdown">{{.}}</div>`),
+			Outline: OutlineConfig{
+				MinLevel: 2,
+				MaxLevel: 3,
+			},
 		},
 		Html: HtmlRenderConfig{
-			BlockTemplate: createRenderTemplate("html-default", `<div id="content html">{{.}}</div>"`),
+			BlockTemplate: defaultTemplate(`<div id="content html">{{.}}</div>`),
+		},
+		Auth: AuthConfig{
+			Realm:  "mdsite",
+			Acl:    DefaultAclFile,
+			Exempt: []string{"/ping"},
+		},
+		Feed: FeedConfig{
+			Size: 20,
+		},
+		Sitemap: SitemapConfig{
+			Size: MaxSitemapSize,
+		},
+		Robots: "User-agent: *\nDisallow:\n",
+		Git: GitConfig{
+			Enabled: true,
+		},
+		Taxonomies: []TaxonomyConfig{
+			{Name: "tags"},
 		},
 	}
 
 	return s
 }
 
-func LoadSiteConfig() (Site, error) {
+// Templates lists every configured template, keyed by its config name.
+func (s *Site) Templates() map[string]*RenderTemplate {
+	all := map[string]*RenderTemplate{
+		"global.pageTemplate":     s.Global.PageTemplate,
+		"global.tocTemplate":      s.Global.TocTemplate,
+		"global.taxonomyTemplate": s.Global.TaxonomyTemplate,
+		"global.termTemplate":     s.Global.TermTemplate,
+		"markdown.blockTemplate":  s.Markdown.BlockTemplate,
+		"html.blockTemplate":      s.Html.BlockTemplate,
+		"toc.pageTemplate":        s.Contents.PageTemplate,
+	}
+	for _, t := range s.Taxonomies {
+		all["taxonomies."+t.Name+".listTemplate"] = t.ListTemplate
+		all["taxonomies."+t.Name+".termTemplate"] = t.TermTemplate
+	}
+
+	for name, t := range all {
+		if t == nil {
+			delete(all, name)
+		}
+	}
+
+	return all
+}
+
+// checkTaxonomies validates the taxonomies, and fills in their defaults.
+func (s *Site) checkTaxonomies() error {
+	paths := make(map[string]bool)
+
+	for n := range s.Taxonomies {
+		t := &s.Taxonomies[n]
+		if t.Name == "" {
+			return fmt.Errorf("taxonomy %d has no name", n)
+		}
+		if t.Path == "" {
+			t.Path = t.Name
+		}
+		if t.Title == "" {
+			t.Title = strings.Title(t.Name)
+		}
+
+		if !taxonomyPath.MatchString(t.Path) || reservedPaths[t.Path] {
+			return fmt.Errorf("taxonomy %s cannot use the path: %s", t.Name, t.Path)
+		}
+		if paths[t.Path] {
+			return fmt.Errorf("taxonomy %s uses the same path as another taxonomy: %s", t.Name, t.Path)
+		}
+		paths[t.Path] = true
+	}
+
+	return nil
+}
+
+func (f *FeedConfig) check() error {
+	if f.Size < 1 {
+		return fmt.Errorf("invalid feed size %d: feeds need at least one page", f.Size)
+	}
+
+	for n := range f.Sections {
+		f.Sections[n] = strings.Trim(f.Sections[n], "/")
+	}
+
+	return nil
+}
+
+func (m SitemapConfig) check() error {
+	if m.Size < 1 || m.Size > MaxSitemapSize {
+		return fmt.Errorf("invalid sitemap size %d: sitemaps list from 1 to %d pages", m.Size, MaxSitemapSize)
+	}
+
+	return nil
+}
+
+func (o OutlineConfig) check() error {
+	if o.MinLevel < 1 || o.MaxLevel > 6 || o.MinLevel > o.MaxLevel {
+		return fmt.Errorf("invalid outline levels %d to %d: levels must be from 1 to 6", o.MinLevel, o.MaxLevel)
+	}
+
+	return nil
+}
+
+// ListTemplateFor gives the template for the page listing every term of the
+// taxonomy.
+func (t TaxonomyConfig) ListTemplateFor(s *Site) *RenderTemplate {
+	if t.ListTemplate != nil {
+		return t.ListTemplate
+	}
+
+	return s.Global.TaxonomyTemplate
+}
+
+// TermTemplateFor gives the template for the page listing the pages for a term.
+func (t TaxonomyConfig) TermTemplateFor(s *Site) *RenderTemplate {
+	if t.TermTemplate != nil {
+		return t.TermTemplate
+	}
+
+	return s.Global.TermTemplate
+}
+
+// TemplateErrors holds the errors for every template that failed to resolve.
+type TemplateErrors []TemplateError
+
+type TemplateError struct {
+	Name string
+	Err  error
+}
+
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("template %s: %s", e.Name, e.Err)
+}
+
+func (e TemplateErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, te := range e {
+		msgs[i] = te.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (s *Site) resolveTemplates(l *TemplateLoader) error {
+	templates := s.Templates()
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs TemplateErrors
+	for _, name := range names {
+		err := templates[name].Resolve(l, name)
+		if err != nil {
+			errs = append(errs, TemplateError{Name: name, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func LoadSiteConfig(v *Values) (Site, error) {
+	return loadSiteConfig(v, yaml.Unmarshal)
+}
+
+// LoadSiteConfigStrict loads the site config like LoadSiteConfig, but rejects
+// any keys that are not part of the config.
+func LoadSiteConfigStrict(v *Values) (Site, error) {
+	return loadSiteConfig(v, yaml.UnmarshalStrict)
+}
+
+func loadSiteConfig(v *Values, unmarshal func([]byte, interface{}) error) (Site, error) {
 	base := defaultSiteConfig()
 
 	// Try to load file data
-	siteFile := filepath.Join(Global().ConfigPath, "site.yml")
+	siteFile := filepath.Join(v.ConfigPath, "site.yml")
 	data, err := ioutil.ReadFile(siteFile)
 	if err != nil {
 		return base, err
 	}
 
 	log.Infof("Loading site config: %s", siteFile)
-	err = yaml.Unmarshal(data, &base)
+	err = unmarshal(data, &base)
+	if err != nil {
+		log.Errorf("Failed to load config: %s", err)
+		return base, err
+	}
+
+	err = base.checkTaxonomies()
+	if err == nil {
+		err = base.Markdown.Outline.check()
+	}
+	if err == nil {
+		err = base.Feed.check()
+	}
+	if err == nil {
+		err = base.Sitemap.check()
+	}
+	if err != nil {
+		log.Errorf("Failed to load config: %s", err)
+		return base, err
+	}
+
+	// Bind the template helpers to this site
+	basePath := v.basePathFor(&base)
+	loader := NewTemplateLoader(v.ConfigPath)
+	loader.Funcs["url"] = func(p string) string {
+		return JoinUrl(basePath, p)
+	}
+
+	err = base.resolveTemplates(loader)
 	if err != nil {
 		log.Errorf("Failed to load config: %s", err)
 		return base, err
@@ -29,6 +29,8 @@ type SiteSuite struct {
 	suite.Suite
 
 	testdataPath string
+	values       *Values
+	loader       *TemplateLoader
 }
 
 func (s *SiteSuite) SetupTest() {
@@ -40,7 +42,8 @@ func (s *SiteSuite) SetupTest() {
 	s.testdataPath = filepath.Join(basedir, "testdata")
 	v.ConfigPath = s.testdataPath
 
-	SetGlobal(v)
+	s.values = v
+	s.loader = NewTemplateLoader(v.ConfigPath)
 }
 
 func TestSiteSuite(t *testing.T) {
@@ -91,6 +94,7 @@ func (s *SiteSuite) TestRenderTemplateParsing_String() {
 	err := yaml.Unmarshal([]byte("<section>{{.}}</section>"), &rt)
 
 	s.Require().NoError(err)
+	s.Require().NoError(rt.Resolve(s.loader, "test"))
 
 	buf := bytes.Buffer{}
 	err = rt.tpl.Execute(&buf, "TEST")
@@ -100,7 +104,7 @@ func (s *SiteSuite) TestRenderTemplateParsing_String() {
 }
 
 func (s *SiteSuite) TestResolveTemplate_String() {
-	t, err := resolveTemplate("test", "<section>{{.}}</section>")
+	t, err := s.loader.Resolve("test", "<section>{{.}}</section>")
 	s.Require().NoError(err)
 
 	buf := bytes.Buffer{}
@@ -110,9 +114,24 @@ func (s *SiteSuite) TestResolveTemplate_String() {
 	s.Equal("<section>TEST</section>", buf.String())
 }
 
+func (s *SiteSuite) TestResolveTemplate_UrlHelper() {
+	s.loader.Funcs["url"] = func(p string) string {
+		return JoinUrl("/docs", p)
+	}
+
+	t, err := s.loader.Resolve("test", `<a href="{{url "/toc"}}">{{.}}</a>`)
+	s.Require().NoError(err)
+
+	buf := bytes.Buffer{}
+	err = t.Execute(&buf, "TEST")
+
+	s.Require().NoError(err)
+	s.Equal(`<a href="/docs/toc">TEST</a>`, buf.String())
+}
+
 func (s *SiteSuite) TestResolveTemplate_AbsPath() {
-	tmplPath := filepath.Join(Global().ConfigPath, "templates", "div_wrapper.tpl.html")
-	t, err := resolveTemplate("test", tmplPath)
+	tmplPath := filepath.Join(s.values.ConfigPath, "templates", "div_wrapper.tpl.html")
+	t, err := s.loader.Resolve("test", tmplPath)
 	s.Require().NoError(err)
 
 	buf := bytes.Buffer{}
@@ -124,7 +143,7 @@ func (s *SiteSuite) TestResolveTemplate_AbsPath() {
 
 func (s *SiteSuite) TestResolveTemplate_RelPath() {
 	tmplPath := filepath.Join("templates", "section_wrapper.tpl.html")
-	t, err := resolveTemplate("test", tmplPath)
+	t, err := s.loader.Resolve("test", tmplPath)
 	s.Require().NoError(err)
 
 	buf := bytes.Buffer{}
@@ -135,27 +154,28 @@ func (s *SiteSuite) TestResolveTemplate_RelPath() {
 }
 
 func (s *SiteSuite) TestResolveTemplate_BadString() {
-	t, err := resolveTemplate("test", "<section>{{{{.}}</section>")
+	t, err := s.loader.Resolve("test", "<section>{{{{.}}</section>")
 
 	s.Error(err)
 	s.Nil(t)
 }
 
 func (s *SiteSuite) TestResolveTemplate_Unknown() {
-	t, err := resolveTemplate("test", "<section></section>")
+	t, err := s.loader.Resolve("test", "<section></section>")
 
 	s.Error(err)
 	s.Nil(t)
 }
 
-func (s *SiteSuite) TestCreateRenderTemplate_Failure() {
-	s.Panics(func() {
-		createRenderTemplate("super-fail", "non-existent-dir/no-file.tmp")
-	})
+func (s *SiteSuite) TestRenderTemplateResolve_Failure() {
+	rt := RenderTemplate{source: "non-existent-dir/no-file.tmp"}
+
+	s.Error(rt.Resolve(s.loader, "super-fail"))
+	s.Error(rt.Execute(&bytes.Buffer{}, "TEST"))
 }
 
 func (s *SiteSuite) TestLoadTemplateFile_Missing() {
-	tmplPath := filepath.Join(Global().ConfigPath, "templates", "missing.tpl.html")
+	tmplPath := filepath.Join(s.values.ConfigPath, "templates", "missing.tpl.html")
 	t, err := loadTemplateFile("test", tmplPath)
 
 	s.Error(err)
@@ -170,6 +190,7 @@ func (s *SiteSuite) TestUnmarshal_String() {
 	err := yaml.Unmarshal([]byte(tmpl), &rt)
 
 	s.Require().NoError(err)
+	s.Require().NoError(rt.Resolve(s.loader, "test"))
 
 	buf := bytes.Buffer{}
 	err = rt.tpl.Execute(&buf, "TEST")
@@ -188,13 +209,14 @@ func (s *SiteSuite) TestUnmarshal_Invalid() {
 }
 
 func (s *SiteSuite) TestUnmarshal_AbsFile() {
-	tmplPath := filepath.Join(Global().ConfigPath, "templates", "div_wrapper.tpl.html")
+	tmplPath := filepath.Join(s.values.ConfigPath, "templates", "div_wrapper.tpl.html")
 
 	rt := RenderTemplate{}
 
 	err := yaml.Unmarshal([]byte(tmplPath), &rt)
 
 	s.Require().NoError(err)
+	s.Require().NoError(rt.Resolve(s.loader, "test"))
 
 	buf := bytes.Buffer{}
 	err = rt.Execute(&buf, "TEST")
@@ -204,8 +226,8 @@ func (s *SiteSuite) TestUnmarshal_AbsFile() {
 }
 
 func (s *SiteSuite) TestLoadSiteConfig() {
-	Global().ConfigPath = filepath.Join(Global().ConfigPath, "sites/test01/config")
-	site, err := LoadSiteConfig()
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/test01/config")
+	site, err := LoadSiteConfig(s.values)
 
 	s.Require().NoError(err)
 	s.Require().NotNil(site)
@@ -219,18 +241,193 @@ func (s *SiteSuite) TestLoadSiteConfig() {
 }
 
 func (s *SiteSuite) TestLoadSiteConfig_BadTemplateFormat() {
-	Global().ConfigPath = filepath.Join(Global().ConfigPath, "sites/fail01/config")
-	site, err := LoadSiteConfig()
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail01/config")
+	site, err := LoadSiteConfig(s.values)
 
 	s.Error(err)
 	s.NotNil(site)
 	s.Equal("Fail01", site.Title)
 }
 
+func (s *SiteSuite) TestLoadSiteConfig_TemplateErrors() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail01/config")
+	_, err := LoadSiteConfig(s.values)
+
+	errs, ok := err.(TemplateErrors)
+	s.Require().True(ok)
+	s.Require().Len(errs, 1)
+	s.Equal("html.blockTemplate", errs[0].Name)
+}
+
+func (s *SiteSuite) TestLoadSiteConfigStrict() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/check01/config")
+
+	_, err := LoadSiteConfigStrict(s.values)
+	s.Error(err)
+	s.Contains(err.Error(), "pageTemplate")
+
+	_, err = LoadSiteConfig(s.values)
+	s.NoError(err)
+}
+
 func (s *SiteSuite) TestLoadSiteConfig_MissingFile() {
-	Global().ConfigPath = filepath.Join(Global().ConfigPath, "sites/fail02/config")
-	site, err := LoadSiteConfig()
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail02/config")
+	site, err := LoadSiteConfig(s.values)
 
 	s.Require().Error(err)
 	s.NotNil(site)
 }
+
+func (s *SiteSuite) TestLoadSiteConfig_Independent() {
+	v1 := Create()
+	v1.ConfigPath = filepath.Join(s.testdataPath, "sites/test01/config")
+	v1.BasePath = "/one"
+
+	v2 := Create()
+	v2.ConfigPath = filepath.Join(s.testdataPath, "sites/test01/config")
+	v2.BasePath = "/two"
+
+	site1, err := LoadSiteConfig(v1)
+	s.Require().NoError(err)
+	site2, err := LoadSiteConfig(v2)
+	s.Require().NoError(err)
+
+	s.True(site1.Markdown.BlockTemplate != site2.Markdown.BlockTemplate)
+	s.NotNil(site1.Global.PageTemplate)
+	s.NotNil(site1.Global.TocTemplate)
+}
+
+func (s *SiteSuite) TestLoadSitesFile() {
+	sf, err := LoadSitesFile(filepath.Join(s.testdataPath, "sites/hosts.yml"))
+
+	s.Require().NoError(err)
+	s.Require().Len(sf.Sites, 2)
+	s.Equal("primary", sf.Sites[0].Name)
+	s.Equal(filepath.Join(s.testdataPath, "sites/test01/site"), sf.Sites[0].SitePath)
+	s.Equal([]string{"*"}, sf.Sites[1].Hosts)
+
+	v := sf.Sites[0].Values(s.values)
+	s.Equal(filepath.Join(s.testdataPath, "sites/test01/config"), v.ConfigPath)
+}
+
+func (s *SiteSuite) TestLoadSitesFile_Missing() {
+	_, err := LoadSitesFile(filepath.Join(s.testdataPath, "sites/none.yml"))
+
+	s.Error(err)
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_Taxonomies() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/taxonomy01/config")
+	site, err := LoadSiteConfig(s.values)
+	s.Require().NoError(err)
+
+	s.Require().Len(site.Taxonomies, 3)
+	s.Equal(TaxonomyConfig{Name: "tags", Path: "tags", Title: "Tags"}, site.Taxonomies[0])
+	s.Equal("services", site.Taxonomies[1].Path)
+	s.Equal("Services", site.Taxonomies[1].Title)
+	s.Equal("audience", site.Taxonomies[2].Path)
+
+	s.True(site.Taxonomies[0].TermTemplateFor(&site) == site.Global.TermTemplate)
+	s.True(site.Taxonomies[1].TermTemplateFor(&site) == site.Taxonomies[1].TermTemplate)
+	s.True(site.Taxonomies[1].ListTemplateFor(&site) == site.Global.TaxonomyTemplate)
+	s.Contains(site.Templates(), "taxonomies.service.termTemplate")
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_DefaultTaxonomies() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/test01/config")
+	site, err := LoadSiteConfig(s.values)
+	s.Require().NoError(err)
+
+	s.Equal([]TaxonomyConfig{{Name: "tags", Path: "tags", Title: "Tags"}}, site.Taxonomies)
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_ReservedTaxonomyPath() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail05/config")
+	_, err := LoadSiteConfig(s.values)
+
+	s.Require().Error(err)
+	s.Contains(err.Error(), "topics")
+}
+
+func (s *SiteSuite) TestCheckTaxonomies_Reserved() {
+	for _, p := range []string{"api", "toc", "feeds", "feed.atom", "feed.rss", "feed.json", "sitemap",
+		"sitemap.xml", "robots.txt", "_mdsite", "ping", "metrics", "healthz", "readyz"} {
+		site := Site{Taxonomies: []TaxonomyConfig{{Name: "terms", Path: p}}}
+		s.Error(site.checkTaxonomies(), p)
+	}
+
+	site := Site{Taxonomies: []TaxonomyConfig{{Name: "terms", Path: "pings"}}}
+	s.NoError(site.checkTaxonomies())
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_Outline() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/outline01/config")
+	site, err := LoadSiteConfig(s.values)
+	s.Require().NoError(err)
+
+	s.Equal(OutlineConfig{MinLevel: 2, MaxLevel: 3}, site.Markdown.Outline)
+	s.False(site.Markdown.Outline.Includes(1))
+	s.True(site.Markdown.Outline.Includes(3))
+	s.False(site.Markdown.Outline.Includes(4))
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_InvalidOutline() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail06/config")
+	_, err := LoadSiteConfig(s.values)
+
+	s.Require().Error(err)
+	s.Contains(err.Error(), "outline levels")
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_Feed() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/feed01/config")
+	site, err := LoadSiteConfig(s.values)
+	s.Require().NoError(err)
+
+	s.Equal(FeedConfig{Size: 3, Sections: []string{"ops", ""}, Content: true}, site.Feed)
+	s.True(site.Feed.Includes("ops"))
+	s.True(site.Feed.Includes(""))
+	s.False(site.Feed.Includes("dev"))
+
+	s.values.SiteConfig = site
+	s.Equal("https://docs.example.com", s.values.SiteOrigin())
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_DefaultFeed() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/test01/config")
+	site, err := LoadSiteConfig(s.values)
+	s.Require().NoError(err)
+
+	s.Equal(20, site.Feed.Size)
+	s.True(site.Git.Enabled)
+	s.True(site.Feed.Includes("anything"))
+
+	s.values.SiteConfig = site
+	s.Equal("", s.values.SiteOrigin())
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_InvalidFeed() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail07/config")
+	_, err := LoadSiteConfig(s.values)
+
+	s.Require().Error(err)
+	s.Contains(err.Error(), "feed size")
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_Sitemap() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/sitemap01/config")
+	site, err := LoadSiteConfig(s.values)
+	s.Require().NoError(err)
+
+	s.Equal(2, site.Sitemap.Size)
+	s.False(site.Git.Enabled)
+	s.Equal("User-agent: *\nDisallow: /api/\n", site.Robots)
+}
+
+func (s *SiteSuite) TestLoadSiteConfig_InvalidSitemap() {
+	s.values.ConfigPath = filepath.Join(s.values.ConfigPath, "sites/fail08/config")
+	_, err := LoadSiteConfig(s.values)
+
+	s.Require().Error(err)
+	s.Contains(err.Error(), "sitemap size")
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Every setting can be given as a flag, an MDSITE_* environment variable, or
+// a key in the server config file, named after the flag. Flags take priority
+// over the environment, which takes priority over the file, which takes
+// priority over the defaults.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+
+	EnvPrefix           = "MDSITE_"
+	DefaultServerConfig = "mdsite.yml"
+)
+
+// Flags that control how the settings are loaded, rather than the server
+var loaderFlags = map[string]bool{
+	"server-config": true,
+	"print-config":  true,
+}
+
+var secretFlags = map[string]bool{
+	"admin-token": true,
+}
+
+// EnvName gives the environment variable for a flag, so that "tls-cert" is
+// read from MDSITE_TLS_CERT.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// applySources fills in every setting that was not given as a flag, from the
+// environment or the server config file.
+func (v *Values) applySources(fs *pflag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	fileValues, err := v.readServerConfig(fs, lookupEnv)
+	if err != nil {
+		return err
+	}
+
+	v.sources = make(map[string]string)
+
+	for _, name := range v.settings {
+		f := fs.Lookup(name)
+		if f == nil || loaderFlags[name] {
+			continue
+		}
+
+		if f.Changed {
+			v.sources[name] = SourceFlag
+			continue
+		}
+
+		if value, ok := lookupEnv(EnvName(name)); ok {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid %s: %s", EnvName(name), err)
+			}
+			v.sources[name] = SourceEnv
+			continue
+		}
+
+		if value, ok := fileValues[name]; ok {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid %s in %s: %s", name, v.ServerConfig, err)
+			}
+			v.sources[name] = SourceFile
+			continue
+		}
+
+		v.sources[name] = SourceDefault
+	}
+
+	return nil
+}
+
+// readServerConfig loads the server config file, if there is one. A missing
+// file is only an error if it was asked for explicitly.
+func (v *Values) readServerConfig(fs *pflag.FlagSet, lookupEnv func(string) (string, bool)) (map[string]string, error) {
+	explicit := fs.Changed("server-config")
+	if !explicit {
+		if path, ok := lookupEnv(EnvName("server-config")); ok {
+			v.ServerConfig = path
+			explicit = true
+		}
+	}
+	if v.ServerConfig == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(v.ServerConfig)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			v.ServerConfig = ""
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", v.ServerConfig, err)
+	}
+
+	known := make(map[string]bool)
+	for _, name := range v.settings {
+		known[name] = !loaderFlags[name]
+	}
+
+	values := make(map[string]string)
+	for key, value := range raw {
+		if !known[key] {
+			return nil, fmt.Errorf("%s: unknown setting: %s", v.ServerConfig, key)
+		}
+
+		switch vt := value.(type) {
+		case []interface{}:
+			items := make([]string, len(vt))
+			for i, item := range vt {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(vt)
+		}
+	}
+
+	return values, nil
+}
+
+// Source reports where the effective value of a setting came from.
+func (v *Values) Source(flag string) string {
+	if s, ok := v.sources[flag]; ok {
+		return s
+	}
+
+	return SourceDefault
+}
+
+// WriteSettings lists the effective value of every setting, and where it
+// came from.
+func (v *Values) WriteSettings(w io.Writer) error {
+	return v.writeSettings(pflag.CommandLine, w)
+}
+
+func (v *Values) writeSettings(fs *pflag.FlagSet, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if v.ServerConfig != "" {
+		fmt.Fprintf(tw, "# Server config: %s\n", v.ServerConfig)
+	}
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE\tENVIRONMENT")
+
+	names := make([]string, 0, len(v.settings))
+	for _, name := range v.settings {
+		if !loaderFlags[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+
+		value := f.Value.String()
+		if secretFlags[name] && value != "" {
+			value = redacted
+		}
+		if value == "" {
+			value = `""`
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, value, v.Source(name), EnvName(name))
+	}
+
+	return tw.Flush()
+}
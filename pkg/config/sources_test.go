@@ -0,0 +1,153 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type SourcesTestSuite struct {
+	suite.Suite
+	tempDir string
+	env     map[string]string
+}
+
+func TestSourcesTestSuite(t *testing.T) {
+	suite.Run(t, new(SourcesTestSuite))
+}
+
+func (t *SourcesTestSuite) SetupTest() {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ContinueOnError)
+
+	dir, err := ioutil.TempDir("", "mdsite-settings")
+	t.Require().NoError(err)
+	t.tempDir = dir
+	t.env = map[string]string{}
+}
+
+func (t *SourcesTestSuite) TearDownTest() {
+	os.RemoveAll(t.tempDir)
+}
+
+func (t *SourcesTestSuite) lookupEnv(name string) (string, bool) {
+	v, ok := t.env[name]
+	return v, ok
+}
+
+func (t *SourcesTestSuite) writeFile(content string) string {
+	path := filepath.Join(t.tempDir, "mdsite.yml")
+	t.Require().NoError(ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func (t *SourcesTestSuite) load(args ...string) (*Values, error) {
+	v := Create()
+	SetupFlags(v)
+
+	t.Require().NoError(pflag.CommandLine.Parse(args))
+
+	return v, v.applySources(pflag.CommandLine, t.lookupEnv)
+}
+
+func (t *SourcesTestSuite) TestEnvName() {
+	t.Equal("MDSITE_PORT", EnvName("port"))
+	t.Equal("MDSITE_TLS_CERT", EnvName("tls-cert"))
+}
+
+func (t *SourcesTestSuite) TestPrecedence() {
+	path := t.writeFile("port: 8001\nsite: /srv/file-site\nbase-path: /file\ntls-redirect: true\n")
+	t.env["MDSITE_PORT"] = "8002"
+	t.env["MDSITE_SITE"] = "/srv/env-site"
+
+	v, err := t.load("--server-config", path, "--port", "8003")
+	t.Require().NoError(err)
+
+	t.Equal(uint16(8003), v.ListenPort)
+	t.Equal(SourceFlag, v.Source("port"))
+	t.Equal("/srv/env-site", v.SitePath)
+	t.Equal(SourceEnv, v.Source("site"))
+	t.Equal("/file", v.BasePath)
+	t.Equal(SourceFile, v.Source("base-path"))
+	t.True(v.TlsRedirect)
+	t.Equal(DefaultSiteConfig, v.ConfigPath)
+	t.Equal(SourceDefault, v.Source("config"))
+}
+
+func (t *SourcesTestSuite) TestFile_Lists() {
+	path := t.writeFile("access-log-fields: [id, status]\nlisten: unix:/run/mdsite.sock\nsocket-mode: \"0600\"\n")
+	t.env["MDSITE_SERVER_CONFIG"] = path
+
+	v, err := t.load()
+	t.Require().NoError(err)
+
+	t.Equal([]string{"id", "status"}, v.AccessLogFields)
+	t.Equal("/run/mdsite.sock", v.ListenSocket)
+	t.Equal(os.FileMode(0600), v.SocketMode)
+}
+
+func (t *SourcesTestSuite) TestFile_Errors() {
+	path := t.writeFile("prot: 80\n")
+	_, err := t.load("--server-config", path)
+	t.Error(err)
+
+	t.SetupTest()
+	path = t.writeFile("port: eighty\n")
+	_, err = t.load("--server-config", path)
+	t.Error(err)
+
+	t.SetupTest()
+	_, err = t.load("--server-config", filepath.Join(t.tempDir, "missing.yml"))
+	t.Error(err)
+}
+
+func (t *SourcesTestSuite) TestFile_DefaultMissing() {
+	v, err := t.load()
+	t.Require().NoError(err)
+
+	t.Empty(v.ServerConfig)
+}
+
+func (t *SourcesTestSuite) TestEnv_Invalid() {
+	t.env["MDSITE_PORT"] = "not-a-port"
+
+	_, err := t.load()
+	t.Error(err)
+}
+
+func (t *SourcesTestSuite) TestWriteSettings() {
+	t.env["MDSITE_ADMIN_TOKEN"] = "hunter2"
+
+	v, err := t.load("--port", "8080")
+	t.Require().NoError(err)
+
+	buf := &bytes.Buffer{}
+	t.Require().NoError(v.writeSettings(pflag.CommandLine, buf))
+
+	out := buf.String()
+	t.Regexp(`(?m)^port\s+8080\s+flag\s+MDSITE_PORT$`, out)
+	t.Regexp(`(?m)^admin-token\s+\[redacted\]\s+env\s+MDSITE_ADMIN_TOKEN$`, out)
+	t.Regexp(`(?m)^site\s+\.\s+default\s+MDSITE_SITE$`, out)
+	t.NotContains(out, "hunter2")
+	t.NotContains(out, "print-config")
+}
@@ -17,30 +17,92 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/apex/log"
 	"github.com/spf13/pflag"
+	"io"
 	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 )
 
 const (
 	DefaultSitePath          = "."
 	DefaultSiteConfig        = "../config"
 	DefaultPort       uint16 = 80
+	DefaultTlsPort    uint16 = 443
+
+	DefaultSocketMode os.FileMode = 0660
+	UnixSocketPrefix              = "unix:"
+
+	AdminTokenEnv = EnvPrefix + "ADMIN_TOKEN"
+	redacted      = "[redacted]"
 )
 
+// Access log formats
+const (
+	AccessLogJson     = "json"
+	AccessLogLogfmt   = "logfmt"
+	AccessLogCombined = "combined"
+	AccessLogOff      = "off"
+)
+
+// Log outputs, shared by the application and access logs
+const (
+	LogStderr = "stderr"
+	LogStdout = "stdout"
+)
+
+// AccessLogFieldNames lists the fields that can be recorded in json and
+// logfmt access logs.
+var AccessLogFieldNames = []string{"id", "client", "host", "method", "uri", "user", "page", "mode", "status", "bytes", "latency", "referer", "agent"}
+
+var DefaultAccessLogFields = []string{"id", "client", "method", "uri", "user", "page", "mode", "status", "bytes", "latency"}
+
 var DefaultIp = net.IPv4(0, 0, 0, 0)
 
 type Values struct {
 	SitePath   string
 	ConfigPath string
+	SitesFile  string
 	ListenIp   net.IP
 	ListenPort uint16
 
+	BasePath string
+
+	ListenSocket     string
+	SocketMode       os.FileMode
+	InheritListeners bool
+
+	TlsCert       string
+	TlsKey        string
+	TlsPort       uint16
+	TlsRedirect   bool
+	TlsSelfSigned bool
+
+	AccessLogFormat string
+	AccessLogFields []string
+	LogOutput       string
+	// Replaces the log output, for servers embedded in another program
+	LogWriter io.Writer
+
+	AdminListen string
+	AdminToken  string
+
+	DevMode  bool
 	TestMode bool
 
+	ServerConfig string
+	PrintConfig  bool
+
 	SiteConfig Site
+
+	settings []string
+	sources  map[string]string
 }
 
 func Create() *Values {
@@ -50,8 +112,19 @@ func Create() *Values {
 		ListenIp:   DefaultIp,
 		ListenPort: DefaultPort,
 
+		SocketMode:       DefaultSocketMode,
+		InheritListeners: true,
+
+		TlsPort: DefaultTlsPort,
+
+		AccessLogFormat: AccessLogLogfmt,
+		AccessLogFields: DefaultAccessLogFields,
+		LogOutput:       LogStderr,
+
 		TestMode: false,
 
+		ServerConfig: DefaultServerConfig,
+
 		SiteConfig: Site{},
 	}
 
@@ -64,10 +137,36 @@ func SetupFlags(v *Values) {
 	// Initialize flags
 	pflag.StringVar(&v.SitePath, "site", DefaultSitePath, "The path to the directory containing the site to serve")
 	pflag.StringVar(&v.ConfigPath, "config", DefaultSiteConfig, "The path to the directory containing the site configuration")
+	pflag.StringVar(&v.SitesFile, "sites", "", "The path to a file declaring several sites to serve by host name")
 	pflag.Uint16Var(&v.ListenPort, "port", DefaultPort, "The port for unencrypted connections")
-	pflag.IPVar(&v.ListenIp, "listen", DefaultIp, "The host IP to listen on for connections")
+	pflag.StringVar(&v.BasePath, "base-path", "", "The URL path prefix to serve the site under (overrides the site baseUrl)")
+	pflag.Var(&listenValue{v: v}, "listen", "The host IP to listen on for connections, or unix:<path> for a unix socket")
+	pflag.Var(&fileModeValue{mode: &v.SocketMode}, "socket-mode", "The file permissions (octal) for a unix socket")
+	pflag.BoolVar(&v.InheritListeners, "inherit-listeners", true, "Serve on listeners passed in by the service manager (LISTEN_FDS)")
+
+	pflag.StringVar(&v.TlsCert, "tls-cert", "", "The path to a PEM encoded certificate for encrypted connections")
+	pflag.StringVar(&v.TlsKey, "tls-key", "", "The path to the PEM encoded private key for the TLS certificate")
+	pflag.Uint16Var(&v.TlsPort, "tls-port", DefaultTlsPort, "The port for encrypted connections")
+	pflag.BoolVar(&v.TlsRedirect, "tls-redirect", false, "Redirect unencrypted requests to the encrypted port")
+	pflag.BoolVar(&v.TlsSelfSigned, "tls-self-signed", false, "Serve encrypted connections using a generated, self-signed certificate (development only)")
 
+	pflag.StringVar(&v.AccessLogFormat, "access-log-format", AccessLogLogfmt, "The access log format: json, logfmt, combined or off")
+	pflag.StringSliceVar(&v.AccessLogFields, "access-log-fields", DefaultAccessLogFields, "The fields recorded in json and logfmt access logs: "+strings.Join(AccessLogFieldNames, ","))
+	pflag.StringVar(&v.LogOutput, "log-output", LogStderr, "Where application and access logs are written: stderr or stdout")
+
+	pflag.StringVar(&v.AdminListen, "admin-listen", "", "A separate address (ip:port or unix:<path>) to serve /metrics and the admin API on, instead of the site listener")
+	pflag.StringVar(&v.AdminToken, "admin-token", "", "The bearer token that enables the admin API (prefer the "+AdminTokenEnv+" environment variable)")
+
+	pflag.BoolVar(&v.DevMode, "dev", false, "Enable development mode, which highlights broken links in rendered pages")
 	pflag.BoolVar(&v.TestMode, "test", false, "Enable testing mode (integration, not unit)")
+
+	pflag.StringVar(&v.ServerConfig, "server-config", DefaultServerConfig, "The path to a YAML file of server settings, keyed by flag name")
+	pflag.BoolVar(&v.PrintConfig, "print-config", false, "Print the effective settings and where each came from, then exit")
+
+	v.settings = nil
+	pflag.CommandLine.VisitAll(func(f *pflag.Flag) {
+		v.settings = append(v.settings, f.Name)
+	})
 }
 
 func (v *Values) Load() {
@@ -84,7 +183,22 @@ func (v *Values) LoadAll(a []string) {
 		log.Fatalf("Error reading command options: %s", err)
 	}
 
+	// Fill in anything not given as a flag
+	err = v.applySources(pflag.CommandLine, os.LookupEnv)
+	if err != nil {
+		log.Fatalf("Error reading server settings: %s", err)
+	}
+
 	// Post-processing, overrides, and inference
+	if (v.TlsCert == "") != (v.TlsKey == "") {
+		log.Fatalf("Both --tls-cert and --tls-key must be supplied to enable TLS")
+	}
+	if err := v.checkAccessLog(); err != nil {
+		log.Fatalf("Invalid access log settings: %s", err)
+	}
+	if v.LogOutput != LogStderr && v.LogOutput != LogStdout {
+		log.Fatalf("Unknown log output: %s", v.LogOutput)
+	}
 
 	// Test Mode enables ephemeral port and so forth
 	if v.TestMode {
@@ -97,4 +211,174 @@ func (v *Values) EnableTestMode() {
 
 	log.Info("Enabling test mode.")
 	v.ListenPort = 0
+	v.TlsPort = 0
+}
+
+// BaseUrl returns the normalized path prefix the site is served under. The
+// --base-path flag takes priority over the baseUrl in the site config. The
+// root prefix is returned as an empty string.
+func (v *Values) BaseUrl() string {
+	return v.basePathFor(&v.SiteConfig)
+}
+
+func (v *Values) basePathFor(s *Site) string {
+	base := v.BasePath
+	if base == "" {
+		base = s.BaseUrl
+	}
+
+	return NormalizeBasePath(base)
+}
+
+// SiteOrigin is the scheme and host of the baseUrl in the site config, when
+// it is a full URL, such as "https://docs.example.com".
+func (v *Values) SiteOrigin() string {
+	u, err := url.Parse(v.SiteConfig.BaseUrl)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
+
+// SiteUrl builds a link to a site path, honoring the base path prefix.
+func (v *Values) SiteUrl(p string) string {
+	return JoinUrl(v.BaseUrl(), p)
+}
+
+// JoinUrl prefixes a site path with a normalized base path. Absolute URLs are
+// returned unchanged.
+func JoinUrl(base string, p string) string {
+	if strings.Contains(p, "://") || strings.HasPrefix(p, "//") {
+		return p
+	}
+
+	return base + "/" + strings.TrimPrefix(p, "/")
+}
+
+func NormalizeBasePath(base string) string {
+	if u, err := url.Parse(base); err == nil && u.Scheme != "" {
+		base = u.Path
+	}
+
+	base = strings.Trim(base, "/")
+	if base == "" {
+		return ""
+	}
+
+	return "/" + base
+}
+
+func (v *Values) ListenAddress() string {
+	if v.ListenSocket != "" {
+		return UnixSocketPrefix + v.ListenSocket
+	}
+
+	return fmt.Sprintf("%s:%d", v.ListenIp.String(), v.ListenPort)
+}
+
+func (v *Values) TlsEnabled() bool {
+	return v.TlsSelfSigned || (v.TlsCert != "" && v.TlsKey != "")
+}
+
+// Redacted copies the values with any secrets removed, so they can be shown
+// to operators.
+func (v *Values) Redacted() *Values {
+	r := *v
+	if r.AdminToken != "" {
+		r.AdminToken = redacted
+	}
+
+	return &r
+}
+
+// Logs is where both the application and access logs are written.
+func (v *Values) Logs() io.Writer {
+	switch {
+	case v.LogWriter != nil:
+		return v.LogWriter
+	case v.LogOutput == LogStdout:
+		return os.Stdout
+	}
+
+	return os.Stderr
+}
+
+func (v *Values) checkAccessLog() error {
+	switch v.AccessLogFormat {
+	case AccessLogJson, AccessLogLogfmt, AccessLogCombined, AccessLogOff:
+	default:
+		return fmt.Errorf("unknown format: %s", v.AccessLogFormat)
+	}
+
+	for _, f := range v.AccessLogFields {
+		known := false
+		for _, name := range AccessLogFieldNames {
+			known = known || f == name
+		}
+		if !known {
+			return fmt.Errorf("unknown field: %s", f)
+		}
+	}
+
+	return nil
+}
+
+type listenValue struct {
+	v *Values
+}
+
+func (l *listenValue) String() string {
+	if l.v.ListenSocket != "" {
+		return UnixSocketPrefix + l.v.ListenSocket
+	}
+
+	return l.v.ListenIp.String()
+}
+
+func (l *listenValue) Set(s string) error {
+	if strings.HasPrefix(s, UnixSocketPrefix) {
+		socket := strings.TrimPrefix(s, UnixSocketPrefix)
+		if socket == "" {
+			return errors.New("a unix socket path is required")
+		}
+
+		l.v.ListenSocket = socket
+		return nil
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return fmt.Errorf("invalid listen address: %s", s)
+	}
+
+	l.v.ListenIp = ip
+	l.v.ListenSocket = ""
+	return nil
+}
+
+func (l *listenValue) Type() string {
+	return "address"
+}
+
+type fileModeValue struct {
+	mode *os.FileMode
+}
+
+func (m *fileModeValue) String() string {
+	return fmt.Sprintf("%04o", uint32(*m.mode))
+}
+
+func (m *fileModeValue) Set(s string) error {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode: %s", s)
+	}
+
+	*m.mode = os.FileMode(mode) & os.ModePerm
+	return nil
+}
+
+func (m *fileModeValue) Type() string {
+	return "mode"
 }
@@ -17,6 +17,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/suite"
@@ -69,6 +70,17 @@ func (t *ValuesTestSuite) TestValueParse_Interface() {
 	t.Equal(i, v.ListenIp)
 }
 
+func (t *ValuesTestSuite) TestValueParse_UnixSocket() {
+	v := Create()
+	SetupFlags(v)
+
+	loadVarArgs(v, "--listen", "unix:/run/mdsite.sock", "--socket-mode", "0666")
+
+	t.Equal("/run/mdsite.sock", v.ListenSocket)
+	t.Equal(os.FileMode(0666), v.SocketMode)
+	t.Equal("unix:/run/mdsite.sock", v.ListenAddress())
+}
+
 func (t *ValuesTestSuite) TestValueParse_Config() {
 	v := Create()
 	SetupFlags(v)
@@ -79,6 +91,115 @@ func (t *ValuesTestSuite) TestValueParse_Config() {
 	t.Equal(c, v.ConfigPath)
 }
 
+func (t *ValuesTestSuite) TestValueParse_Tls() {
+	v := Create()
+	SetupFlags(v)
+
+	loadVarArgs(v, "--tls-cert", "/etc/cert.pem", "--tls-key", "/etc/key.pem", "--tls-port", "8443", "--tls-redirect")
+
+	t.True(v.TlsEnabled())
+	t.Equal("/etc/cert.pem", v.TlsCert)
+	t.Equal("/etc/key.pem", v.TlsKey)
+	t.Equal(uint16(8443), v.TlsPort)
+	t.True(v.TlsRedirect)
+}
+
+func (t *ValuesTestSuite) TestValueParse_TlsDisabled() {
+	v := Create()
+	SetupFlags(v)
+
+	loadVarArgs(v)
+
+	t.False(v.TlsEnabled())
+	t.Equal(DefaultTlsPort, v.TlsPort)
+}
+
+func (t *ValuesTestSuite) TestValueParse_AccessLog() {
+	v := Create()
+	SetupFlags(v)
+
+	loadVarArgs(v, "--access-log-format", "json", "--access-log-fields", "id,status,latency")
+
+	t.Equal(AccessLogJson, v.AccessLogFormat)
+	t.Equal([]string{"id", "status", "latency"}, v.AccessLogFields)
+}
+
+func (t *ValuesTestSuite) TestCheckAccessLog() {
+	v := Create()
+	t.NoError(v.checkAccessLog())
+
+	v.AccessLogFields = []string{"id", "bogus"}
+	t.Error(v.checkAccessLog())
+
+	v = Create()
+	v.AccessLogFormat = "xml"
+	t.Error(v.checkAccessLog())
+}
+
+func (t *ValuesTestSuite) TestLogs() {
+	v := Create()
+	t.Equal(os.Stderr, v.Logs())
+
+	SetupFlags(v)
+	loadVarArgs(v, "--log-output", "stdout")
+	t.Equal(os.Stdout, v.Logs())
+
+	buf := &bytes.Buffer{}
+	v.LogWriter = buf
+	t.Equal(buf, v.Logs())
+}
+
+func (t *ValuesTestSuite) TestValueParse_AdminToken() {
+	os.Setenv(AdminTokenEnv, "from-env")
+	defer os.Unsetenv(AdminTokenEnv)
+
+	v := Create()
+	SetupFlags(v)
+	loadVarArgs(v)
+	t.Equal("from-env", v.AdminToken)
+
+	t.SetupTest()
+	v = Create()
+	SetupFlags(v)
+	loadVarArgs(v, "--admin-token", "from-flag")
+	t.Equal("from-flag", v.AdminToken)
+
+	r := v.Redacted()
+	t.Equal("[redacted]", r.AdminToken)
+	t.Equal("from-flag", v.AdminToken)
+}
+
+func (t *ValuesTestSuite) TestValueParse_BasePath() {
+	v := Create()
+	SetupFlags(v)
+
+	loadVarArgs(v, "--base-path", "docs/")
+
+	t.Equal("/docs", v.BaseUrl())
+	t.Equal("/docs/toc", v.SiteUrl("/toc"))
+	t.Equal("/docs/", v.SiteUrl("/"))
+}
+
+func (t *ValuesTestSuite) TestBaseUrl_SiteConfig() {
+	v := Create()
+	v.SiteConfig.BaseUrl = "https://intranet/docs/"
+
+	t.Equal("/docs", v.BaseUrl())
+	t.Equal("/docs/info/page", v.SiteUrl("info/page"))
+
+	v.BasePath = "/override"
+	t.Equal("/override", v.BaseUrl())
+}
+
+func (t *ValuesTestSuite) TestBaseUrl_Root() {
+	v := Create()
+
+	t.Equal("", v.BaseUrl())
+	t.Equal("/toc", v.SiteUrl("/toc"))
+	t.Equal("/", v.SiteUrl(""))
+	t.Equal("https://example.com/x", v.SiteUrl("https://example.com/x"))
+}
+
 func TestValueTestSuite(t *testing.T) {
 	suite.Run(t, new(ValuesTestSuite))
 }
@@ -16,18 +16,5 @@
 
 package config
 
-var globalConf *Values
-
-func Global() *Values {
-	if globalConf == nil {
-		panic("No global configuration loaded")
-	}
-	return globalConf
-}
-
-func SetGlobal(v *Values) {
-	if v == nil {
-		panic("Cannot set global config to an empty value")
-	}
-	globalConf = v
-}
+// Version is set at build time by the Makefile, using -ldflags -X.
+var Version = "dev"
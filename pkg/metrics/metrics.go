@@ -0,0 +1,322 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency buckets in seconds, suited to a doc server.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds a set of metrics and writes them in the Prometheus text
+// format, in the order they were registered.
+type Registry struct {
+	lock    sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+func (r *Registry) WriteText(w io.Writer) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range r.metrics {
+		m.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+func (d *desc) sample(w *bufio.Writer, suffix string, values []string, extra string, v float64) {
+	w.WriteString(d.name)
+	w.WriteString(suffix)
+
+	pairs := make([]string, 0, len(values)+1)
+	for i, name := range d.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabel(values[i])))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatValue(v) + "\n")
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label(s), got %d", d.name, len(d.labels), len(values)))
+	}
+
+	return strings.Join(values, "\xff")
+}
+
+// CounterVec is a set of counters, partitioned by label values.
+type CounterVec struct {
+	desc
+	lock   sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	count  float64
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		series: make(map[string]*counterSeries),
+	}
+	r.register(c)
+
+	return c
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) Add(n float64, values ...string) {
+	k := c.key(values)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s, ok := c.series[k]
+	if !ok {
+		s = &counterSeries{values: append([]string{}, values...)}
+		c.series[k] = s
+	}
+	s.count += n
+}
+
+func (c *CounterVec) Value(values ...string) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if s, ok := c.series[c.key(values)]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.header(w)
+	for _, k := range sortedKeys(c.series) {
+		s := c.series[k]
+		c.sample(w, "", s.values, "", s.count)
+	}
+}
+
+// HistogramVec is a set of histograms, partitioned by label values.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: b,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	k := h.key(values)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{
+			values: append([]string{}, values...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[k] = s
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count reports the number of observations for a set of label values.
+func (h *HistogramVec) Count(values ...string) uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if s, ok := h.series[h.key(values)]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.header(w)
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		for i, upper := range h.buckets {
+			h.sample(w, "_bucket", s.values, fmt.Sprintf("le=\"%s\"", formatValue(upper)), float64(s.counts[i]))
+		}
+		h.sample(w, "_bucket", s.values, "le=\"+Inf\"", float64(s.count))
+		h.sample(w, "_sum", s.values, "", s.sum)
+		h.sample(w, "_count", s.values, "", float64(s.count))
+	}
+}
+
+// Sample is a single gauge reading.
+type Sample struct {
+	Values []string
+	Value  float64
+}
+
+// GaugeFunc is a gauge that is read when the metrics are written, for values
+// that are owned elsewhere.
+type GaugeFunc struct {
+	desc
+	collect func() []Sample
+}
+
+func (r *Registry) NewGaugeFunc(name string, help string, labels []string, collect func() []Sample) *GaugeFunc {
+	g := &GaugeFunc{
+		desc:    desc{name: name, help: help, kind: "gauge", labels: labels},
+		collect: collect,
+	}
+	r.register(g)
+
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w)
+	for _, s := range g.collect() {
+		g.key(s.Values)
+		g.sample(w, "", s.Values, "", s.Value)
+	}
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+
+	switch series := m.(type) {
+	case map[string]*counterSeries:
+		for k := range series {
+			keys = append(keys, k)
+		}
+	case map[string]*histogramSeries:
+		for k := range series {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
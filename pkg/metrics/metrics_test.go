@@ -0,0 +1,98 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (t *MetricsTestSuite) write(r *Registry) string {
+	buf := &bytes.Buffer{}
+	t.Require().NoError(r.WriteText(buf))
+
+	return buf.String()
+}
+
+func (t *MetricsTestSuite) TestCounter() {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "A test counter", "code")
+
+	c.Inc("200")
+	c.Inc("200")
+	c.Add(3, "404")
+
+	t.Equal(float64(2), c.Value("200"))
+	t.Equal(float64(0), c.Value("500"))
+	t.Equal("# HELP test_total A test counter\n"+
+		"# TYPE test_total counter\n"+
+		"test_total{code=\"200\"} 2\n"+
+		"test_total{code=\"404\"} 3\n", t.write(r))
+}
+
+func (t *MetricsTestSuite) TestHistogram() {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_seconds", "A test histogram", []float64{1, 0.1}, "mode")
+
+	h.Observe(0.05, "md")
+	h.Observe(0.5, "md")
+	h.Observe(5, "md")
+
+	t.Equal(uint64(3), h.Count("md"))
+	t.Equal("# HELP test_seconds A test histogram\n"+
+		"# TYPE test_seconds histogram\n"+
+		"test_seconds_bucket{mode=\"md\",le=\"0.1\"} 1\n"+
+		"test_seconds_bucket{mode=\"md\",le=\"1\"} 2\n"+
+		"test_seconds_bucket{mode=\"md\",le=\"+Inf\"} 3\n"+
+		"test_seconds_sum{mode=\"md\"} 5.55\n"+
+		"test_seconds_count{mode=\"md\"} 3\n", t.write(r))
+}
+
+func (t *MetricsTestSuite) TestGaugeFunc() {
+	r := NewRegistry()
+	r.NewGaugeFunc("test_pages", "Pages", []string{"site"}, func() []Sample {
+		return []Sample{{Values: []string{`a "quoted"\site`}, Value: 12}}
+	})
+	r.NewGaugeFunc("test_up", "Up", nil, func() []Sample {
+		return []Sample{{Value: 1}}
+	})
+
+	t.Equal("# HELP test_pages Pages\n"+
+		"# TYPE test_pages gauge\n"+
+		"test_pages{site=\"a \\\"quoted\\\"\\\\site\"} 12\n"+
+		"# HELP test_up Up\n"+
+		"# TYPE test_up gauge\n"+
+		"test_up 1\n", t.write(r))
+}
+
+func (t *MetricsTestSuite) TestLabelMismatch() {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "A test counter", "code")
+
+	t.Panics(func() {
+		c.Inc("200", "extra")
+	})
+}
@@ -42,7 +42,7 @@ func (r HtmlResource) Render(w io.Writer, data *RenderData) error {
 		return err
 	}
 
-	_, err = w.Write(htData)
+	_, err = w.Write(data.outline(data.rewriteLinks(htData)))
 	if err != nil {
 		log.Errorf("Failed to write html data [%s]: %s", data.Resource, err)
 		return err
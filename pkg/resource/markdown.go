@@ -19,6 +19,7 @@ package resource
 import (
 	"github.com/apex/log"
 	"github.com/gomarkdown/markdown"
+	"github.com/zpxio/mdsite/pkg/site"
 	"io"
 	"io/ioutil"
 )
@@ -42,7 +43,25 @@ func (r MarkdownResource) Render(w io.Writer, data *RenderData) error {
 		return err
 	}
 
-	htData := markdown.ToHTML(mdData, nil, nil)
+	_, mdData = site.SplitFrontMatter(mdData)
+
+	var wiki *wikiPage
+	if data.Wiki != nil {
+		wiki = &wikiPage{wiki: data.Wiki}
+		mdData = wiki.convert(mdData)
+	}
+
+	mdData, toc := markTocs(mdData)
+
+	htData := data.rewriteLinks(markdown.ToHTML(mdData, nil, nil))
+	if wiki != nil {
+		htData = wiki.insert(htData)
+	}
+
+	htData = data.outline(htData)
+	if toc {
+		htData = insertTocs(htData, data.Outline)
+	}
 
 	_, err = w.Write(htData)
 	if err != nil {
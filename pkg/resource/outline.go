@@ -0,0 +1,268 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/zpxio/mdsite/pkg/config"
+	"golang.org/x/net/html"
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+// OutlineClass marks outlines placed in a page with a [TOC] marker.
+const OutlineClass = "mdsite-toc"
+
+// Heading is an entry of a page outline.
+type Heading struct {
+	Level    int
+	Text     string
+	Id       string
+	Children []*Heading
+}
+
+// tocMarker stands in for the outline in Markdown source, until the page has
+// been rendered and its headings are known.
+const tocMarker = "mdsite-toc-"
+
+// HeadingId creates the anchor id for a heading from its rendered text.
+// Letters and numbers are kept in lower case, and any other run of
+// characters becomes a single dash. The Markdown renderer makes no ids of its
+// own, so every heading without an explicit {#id} gets this id, with a
+// number added to repeats on the same page.
+func HeadingId(text string) string {
+	var id []rune
+	dash := false
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if dash && len(id) > 0 {
+				id = append(id, '-')
+			}
+			dash = false
+			id = append(id, unicode.ToLower(r))
+		default:
+			dash = true
+		}
+	}
+
+	return string(id)
+}
+
+func headingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+
+	return 0
+}
+
+// outliner gives every heading in rendered HTML a unique id, and collects
+// the headings within the configured levels into an outline.
+type outliner struct {
+	conf    config.OutlineConfig
+	ids     map[string]bool
+	outline []*Heading
+	open    []*Heading
+}
+
+func newOutliner(conf config.OutlineConfig) *outliner {
+	return &outliner{
+		conf: conf,
+		ids:  make(map[string]bool),
+	}
+}
+
+func (o *outliner) uniqueId(id string) string {
+	if id == "" {
+		id = "section"
+	}
+
+	unique := id
+	for n := 1; o.ids[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", id, n)
+	}
+	o.ids[unique] = true
+
+	return unique
+}
+
+func (o *outliner) add(h *Heading) {
+	if !o.conf.Includes(h.Level) {
+		return
+	}
+
+	// Close any headings at the same level or deeper
+	for len(o.open) > 0 && o.open[len(o.open)-1].Level >= h.Level {
+		o.open = o.open[:len(o.open)-1]
+	}
+
+	if len(o.open) == 0 {
+		o.outline = append(o.outline, h)
+	} else {
+		parent := o.open[len(o.open)-1]
+		parent.Children = append(parent.Children, h)
+	}
+	o.open = append(o.open, h)
+}
+
+func (o *outliner) process(content []byte) []byte {
+	out := bytes.Buffer{}
+
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return out.Bytes()
+		}
+
+		if tt != html.StartTagToken {
+			out.Write(z.Raw())
+			continue
+		}
+
+		raw := z.Raw()
+		start := z.Token()
+		level := headingLevel(start.Data)
+		if level == 0 {
+			out.Write(raw)
+			continue
+		}
+
+		// Collect the heading content up to the matching end tag
+		inner := bytes.Buffer{}
+		text := strings.Builder{}
+		var end []byte
+		for end == nil {
+			tt = z.Next()
+			if tt == html.ErrorToken {
+				break
+			}
+
+			raw = z.Raw()
+			switch tt {
+			case html.EndTagToken:
+				if z.Token().Data == start.Data {
+					end = append([]byte{}, raw...)
+					continue
+				}
+			case html.TextToken:
+				text.Write(z.Text())
+			}
+			inner.Write(raw)
+		}
+
+		h := &Heading{Level: level, Text: strings.TrimSpace(text.String())}
+
+		idAttr := -1
+		for n, a := range start.Attr {
+			if a.Namespace == "" && a.Key == "id" {
+				idAttr = n
+			}
+		}
+		if idAttr >= 0 {
+			h.Id = o.uniqueId(start.Attr[idAttr].Val)
+			start.Attr[idAttr].Val = h.Id
+		} else {
+			h.Id = o.uniqueId(HeadingId(h.Text))
+			start.Attr = append(start.Attr, html.Attribute{Key: "id", Val: h.Id})
+		}
+		o.add(h)
+
+		out.WriteString(start.String())
+		out.Write(inner.Bytes())
+		out.Write(end)
+	}
+}
+
+// OutlineHtml renders an outline as nested lists of links.
+func OutlineHtml(outline []*Heading) template.HTML {
+	if len(outline) == 0 {
+		return ""
+	}
+
+	b := strings.Builder{}
+	writeOutline(&b, outline)
+
+	return template.HTML(b.String())
+}
+
+func writeOutline(b *strings.Builder, headings []*Heading) {
+	b.WriteString("<ul>")
+	for _, h := range headings {
+		fmt.Fprintf(b, `<li><a href="#%s">%s</a>`, html.EscapeString(h.Id), html.EscapeString(h.Text))
+		if len(h.Children) > 0 {
+			writeOutline(b, h.Children)
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</ul>")
+}
+
+// markTocs replaces [TOC] markers on lines of their own in Markdown source,
+// so the outline can be put in their place once the page has been rendered.
+func markTocs(md []byte) ([]byte, bool) {
+	found := false
+
+	md = markdownLines(md, func(line []byte) []byte {
+		if !bytes.EqualFold(bytes.TrimSpace(line), []byte("[TOC]")) {
+			return line
+		}
+
+		found = true
+		return []byte(tocMarker + "\n")
+	})
+
+	return md, found
+}
+
+// insertTocs puts the outline in place of the [TOC] markers.
+func insertTocs(content []byte, outline []*Heading) []byte {
+	toc := []byte(fmt.Sprintf(`<nav class="%s">%s</nav>`, OutlineClass, OutlineHtml(outline)))
+
+	content = bytes.Replace(content, []byte("<p>"+tocMarker+"</p>"), toc, -1)
+	return bytes.Replace(content, []byte(tocMarker), toc, -1)
+}
+
+// markdownLines converts each line of Markdown source outside of fenced code
+// blocks.
+func markdownLines(md []byte, convert func(line []byte) []byte) []byte {
+	out := bytes.Buffer{}
+
+	fence := ""
+	for _, line := range bytes.SplitAfter(md, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+
+		switch {
+		case fence != "":
+			// Inside a fenced code block
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			out.Write(line)
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			out.Write(line)
+		default:
+			out.Write(convert(line))
+		}
+	}
+
+	return out.Bytes()
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type OutlineSuite struct {
+	suite.Suite
+}
+
+func TestOutlineSuite(t *testing.T) {
+	suite.Run(t, new(OutlineSuite))
+}
+
+func (s *OutlineSuite) TestHeadingId() {
+	cases := map[string]string{
+		"Getting Started":       "getting-started",
+		"  Set up, then   run!": "set-up-then-run",
+		"Café & Crème":          "café-crème",
+		"Step 2: Deploy":        "step-2-deploy",
+		"!!!":                   "",
+	}
+
+	for text, id := range cases {
+		s.Equal(id, HeadingId(text), text)
+	}
+}
+
+func (s *OutlineSuite) TestProcess() {
+	o := newOutliner(config.OutlineConfig{MinLevel: 2, MaxLevel: 3})
+	content := o.process([]byte(`<h1>Title</h1><h2>Install</h2><h3>On <em>Linux</em></h3>` +
+		`<h2 id="custom">Usage</h2><h4>Deep</h4><h2>Install</h2><h2>!!!</h2>`))
+
+	s.Equal(`<h1 id="title">Title</h1><h2 id="install">Install</h2><h3 id="on-linux">On <em>Linux</em></h3>`+
+		`<h2 id="custom">Usage</h2><h4 id="deep">Deep</h4><h2 id="install-1">Install</h2><h2 id="section">!!!</h2>`,
+		string(content))
+
+	s.Require().Len(o.outline, 4)
+	s.Equal(&Heading{Level: 2, Text: "Install", Id: "install", Children: []*Heading{
+		{Level: 3, Text: "On Linux", Id: "on-linux"},
+	}}, o.outline[0])
+	s.Equal("custom", o.outline[1].Id)
+	s.Empty(o.outline[1].Children)
+	s.Equal("install-1", o.outline[2].Id)
+}
+
+func (s *OutlineSuite) TestMarkdown_HeadingIds() {
+	dir, err := ioutil.TempDir("", "outline")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "page.md")
+	s.Require().NoError(ioutil.WriteFile(file, []byte("[TOC]\n\n"+
+		"## See [the docs](https://example.com) & more\n\n"+
+		"## Explicit {#chosen}\n\n"+
+		"See [[#See the docs & more]].\n"), 0644))
+
+	data := &RenderData{
+		Resource:      file,
+		Wiki:          testWiki{},
+		OutlineConfig: config.OutlineConfig{MinLevel: 2, MaxLevel: 3},
+	}
+	out := bytes.Buffer{}
+	s.Require().NoError(MarkdownResource{}.Render(&out, data))
+
+	// Ids come from the rendered text, so wiki links to headings match them
+	s.Contains(out.String(), `<h2 id="see-the-docs-more">`)
+	s.Contains(out.String(), `<h2 id="chosen">`)
+	s.Contains(out.String(), `<a href="#see-the-docs-more" class="mdsite-wikilink">`)
+	s.Contains(out.String(), `<nav class="mdsite-toc"><ul><li><a href="#see-the-docs-more">See the docs &amp; more</a></li>`+
+		`<li><a href="#chosen">Explicit</a></li></ul></nav>`)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/site"
+	"github.com/zpxio/mdsite/pkg/util"
+	"io"
+	"path/filepath"
+)
+
+// Pages renders the files of a site, with their links and wiki links
+// resolved against a page index. Renderers are chosen by file extension.
+type Pages struct {
+	conf      *config.Values
+	renderers map[string]Renderer
+}
+
+func NewPages(v *config.Values, renderers map[string]Renderer) *Pages {
+	return &Pages{conf: v, renderers: renderers}
+}
+
+// DefaultRenderers are the renderers every site starts with.
+func DefaultRenderers() map[string]Renderer {
+	return map[string]Renderer{
+		"md":   MarkdownResource{},
+		"txt":  TextResource{},
+		"html": HtmlResource{},
+	}
+}
+
+// Rendered checks whether a page is rendered, rather than being a file that
+// only happens to be in the site.
+func (r *Pages) Rendered(p *site.PageEntry) bool {
+	_, ok := r.renderers[p.Extension]
+	return ok
+}
+
+// Prepare sets up the link handling and outline for rendering a file of the
+// site. Wiki links only resolve to, and embed, pages in the index given, so
+// it should hold only the pages the reader can see. Files already being
+// rendered can't be embedded again.
+func (r *Pages) Prepare(data *RenderData, i *site.PageIndex, rendering map[string]bool) {
+	r.prepare(data, i, rendering)
+}
+
+func (r *Pages) prepare(data *RenderData, i *site.PageIndex, rendering map[string]bool) *wikiLinks {
+	wiki := r.wikiLinks(i, data.Resource, rendering)
+
+	data.RewriteLink = r.sourceLinks(i, data.Resource)
+	data.Wiki = wiki
+	data.OutlineConfig = r.conf.SiteConfig.Markdown.Outline
+
+	return wiki
+}
+
+// sourceLinks creates the link rewriter for a page rendered from a file.
+func (r *Pages) sourceLinks(i *site.PageIndex, rcFile string) func(href string) string {
+	source, err := filepath.Rel(r.conf.SitePath, rcFile)
+	if err != nil {
+		return nil
+	}
+
+	return i.SourceLinks(r.conf, filepath.ToSlash(source), r.Rendered)
+}
+
+// ScanLinks renders every HTML page in a new index to find its links, and
+// checks that the internal ones lead somewhere. Ambiguous wiki links are
+// recorded as well.
+func (r *Pages) ScanLinks(i *site.PageIndex) {
+	for _, p := range i.Pages {
+		renderer, ok := r.renderers[p.Extension]
+		if !ok || renderer.MediaType() != gin.MIMEHTML {
+			continue
+		}
+
+		buf := bytes.Buffer{}
+		data := &RenderData{Resource: filepath.Join(r.conf.SitePath, p.Path)}
+		wiki := r.prepare(data, i, nil)
+		err := renderer.Render(&buf, data)
+		if err != nil {
+			log.Warnf("Failed to render [%s] while checking links: %s", p.Path, err)
+			continue
+		}
+
+		p.AmbiguousLinks = util.UniqueStrings(wiki.ambiguous)
+		for _, name := range p.AmbiguousLinks {
+			log.Warnf("Ambiguous wiki link on [%s]: %s", p.Path, name)
+		}
+
+		p.Links = i.CheckLinks(p, site.ExtractLinks(&buf), func(target string) bool {
+			return site.LinkExists(r.conf, target)
+		})
+
+		for _, l := range p.BrokenLinks() {
+			log.Warnf("Broken link on [%s]: %s", p.Path, l.Href)
+		}
+	}
+}
+
+// wikiLinks resolves the wiki links on a single page against a site index.
+type wikiLinks struct {
+	pages *Pages
+	index *site.PageIndex
+
+	// The pages being rendered, to stop pages from embedding themselves
+	rendering map[string]bool
+	// Names that matched more than one page
+	ambiguous []string
+}
+
+func (r *Pages) wikiLinks(i *site.PageIndex, rcFile string, rendering map[string]bool) *wikiLinks {
+	w := wikiLinks{
+		pages:     r,
+		index:     i,
+		rendering: map[string]bool{rcFile: true},
+	}
+	for f := range rendering {
+		w.rendering[f] = true
+	}
+
+	return &w
+}
+
+func (w *wikiLinks) ResolveWiki(name string) *WikiTarget {
+	pages := w.index.FindWikiPages(name)
+	if len(pages) > 1 {
+		w.ambiguous = append(w.ambiguous, name)
+	}
+	if len(pages) != 1 {
+		return nil
+	}
+
+	p := pages[0]
+	if !w.pages.Rendered(p) {
+		return &WikiTarget{Url: w.pages.conf.SiteUrl(filepath.ToSlash(p.Path))}
+	}
+
+	t := WikiTarget{Url: p.Url, Page: true}
+	if w.pages.renderers[p.Extension].MediaType() == gin.MIMEHTML {
+		t.Embed = func(out io.Writer) error {
+			return w.embed(out, p)
+		}
+	}
+
+	return &t
+}
+
+func (w *wikiLinks) embed(out io.Writer, p *site.PageEntry) error {
+	rcFile := filepath.Join(w.pages.conf.SitePath, p.Path)
+	if w.rendering[rcFile] {
+		return fmt.Errorf("%s is already being rendered", p.Path)
+	}
+
+	data := &RenderData{Resource: rcFile}
+	w.pages.prepare(data, w.index, w.rendering)
+
+	return w.pages.renderers[p.Extension].Render(out, data)
+}
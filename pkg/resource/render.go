@@ -18,13 +18,18 @@ package resource
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/auth"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/site"
 	"html/template"
 )
 
 type RenderData struct {
 	Resource string
 
-	Title string
+	Title   string
+	BaseUrl string
+	User    *auth.User
 
 	Stylesheets []Stylesheet
 	Scripts     []Javascript
@@ -33,6 +38,23 @@ type RenderData struct {
 	MediaType  MediaType
 
 	Content template.HTML
+	// The page being rendered, and where it sits in the site
+	Page        *site.PageEntry
+	Breadcrumbs []site.Crumb
+	Prev        *site.PageEntry
+	Next        *site.PageEntry
+	// The pages that link to this page
+	Backlinks []*site.PageEntry
+
+	// Rewrites the targets of links and images in rendered HTML, if set
+	RewriteLink func(href string) string
+	// Resolves wiki links in Markdown, if set
+	Wiki WikiResolver
+
+	// The headings included in the outline, and the outline of the page once
+	// it has been rendered
+	OutlineConfig config.OutlineConfig
+	Outline       []*Heading
 }
 
 type Stylesheet struct {
@@ -44,10 +66,34 @@ type Javascript struct {
 	weight uint8
 }
 
+// OutlineHtml renders the outline of the page as nested lists of links.
+func (d *RenderData) OutlineHtml() template.HTML {
+	return OutlineHtml(d.Outline)
+}
+
+// outline gives every heading of rendered HTML an id, and collects the
+// outline of the page.
+func (d *RenderData) outline(content []byte) []byte {
+	o := newOutliner(d.OutlineConfig)
+	content = o.process(content)
+	d.Outline = o.outline
+
+	return content
+}
+
+func (d *RenderData) rewriteLinks(content []byte) []byte {
+	if d.RewriteLink == nil {
+		return content
+	}
+
+	return site.RewriteLinks(content, d.RewriteLink)
+}
+
 func InitRenderData(c *gin.Context, resource string) *RenderData {
 	pd := RenderData{
 		Resource:  resource,
 		MediaType: gin.MIMEHTML,
+		User:      auth.ContextUser(c),
 	}
 
 	return &pd
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apex/log"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Classes for the elements created from wiki links
+const (
+	WikiLinkClass = "mdsite-wikilink"
+	NewPageClass  = "mdsite-new-page"
+	EmbedClass    = "mdsite-embed"
+)
+
+// WikiTarget is the page or file a wiki link leads to.
+type WikiTarget struct {
+	Url string
+	// Page is false for files that are served as they are, such as images
+	Page bool
+	// Embed renders the page into another page, if it can be embedded
+	Embed func(w io.Writer) error
+}
+
+// WikiResolver finds the targets of wiki links by name. Names that do not
+// lead to exactly one page resolve to nil.
+type WikiResolver interface {
+	ResolveWiki(name string) *WikiTarget
+}
+
+// Matches [[Name]], [[Name|Alias]] and the ![[Name]] embed form
+var wikiPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+// wikiPage converts the wiki links in Markdown source to HTML. Embedded pages
+// are rendered separately, and are left as placeholders to be filled in by
+// insert once the page itself has been rendered.
+type wikiPage struct {
+	wiki   WikiResolver
+	embeds [][]byte
+}
+
+func (w *wikiPage) convert(md []byte) []byte {
+	return markdownLines(md, func(line []byte) []byte {
+		out := bytes.Buffer{}
+		w.convertLine(&out, line)
+		return out.Bytes()
+	})
+}
+
+// convertLine converts the wiki links in a line, skipping code spans.
+func (w *wikiPage) convertLine(out *bytes.Buffer, line []byte) {
+	for len(line) > 0 {
+		start := bytes.IndexByte(line, '`')
+		if start < 0 {
+			out.Write(w.convertText(line))
+			return
+		}
+		out.Write(w.convertText(line[:start]))
+
+		// Code spans end with a run of backticks of the same length
+		ticks := 1
+		for start+ticks < len(line) && line[start+ticks] == '`' {
+			ticks++
+		}
+		closing := closingTicks(line[start+ticks:], ticks)
+		if closing < 0 {
+			// Not a code span
+			out.Write(line[start : start+ticks])
+			line = line[start+ticks:]
+			continue
+		}
+
+		end := start + ticks + closing
+		out.Write(line[start : end+ticks])
+		line = line[end+ticks:]
+	}
+}
+
+// closingTicks finds a run of exactly n backticks.
+func closingTicks(s []byte, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+
+		run := 1
+		for i+run < len(s) && s[i+run] == '`' {
+			run++
+		}
+		if run == n {
+			return i
+		}
+		i += run
+	}
+
+	return -1
+}
+
+func (w *wikiPage) convertText(text []byte) []byte {
+	return wikiPattern.ReplaceAllFunc(text, func(m []byte) []byte {
+		parts := wikiPattern.FindSubmatch(m)
+		return []byte(w.link(len(parts[1]) > 0, string(parts[2]), string(parts[3])))
+	})
+}
+
+func (w *wikiPage) link(embed bool, name string, alias string) string {
+	name = strings.TrimSpace(name)
+	page, heading := name, ""
+	if h := strings.Index(name, "#"); h >= 0 {
+		page, heading = strings.TrimSpace(name[:h]), strings.TrimSpace(name[h+1:])
+	}
+
+	text := strings.TrimSpace(alias)
+	if text == "" {
+		text = name
+	}
+
+	fragment := ""
+	if heading != "" {
+		fragment = "#" + HeadingId(heading)
+	}
+
+	if page == "" {
+		return anchor(fragment, WikiLinkClass, text)
+	}
+
+	t := w.wiki.ResolveWiki(page)
+	if t == nil {
+		return fmt.Sprintf(`<a class="%s %s" title="New page: %s" style="color: #999; font-style: italic">%s</a>`,
+			WikiLinkClass, NewPageClass, html.EscapeString(page), html.EscapeString(text))
+	}
+
+	if embed && !t.Page {
+		return fmt.Sprintf(`<img class="%s" src="%s" alt="%s"/>`,
+			EmbedClass, html.EscapeString(t.Url), html.EscapeString(text))
+	}
+
+	if embed && t.Embed != nil {
+		content := bytes.Buffer{}
+		err := t.Embed(&content)
+		if err == nil {
+			w.embeds = append(w.embeds, content.Bytes())
+			return w.placeholder(len(w.embeds) - 1)
+		}
+		log.Warnf("Could not embed [%s]: %s", page, err)
+	}
+
+	return anchor(t.Url+fragment, WikiLinkClass, text)
+}
+
+func anchor(href string, class string, text string) string {
+	return fmt.Sprintf(`<a href="%s" class="%s">%s</a>`,
+		html.EscapeString(href), class, html.EscapeString(text))
+}
+
+func (w *wikiPage) placeholder(n int) string {
+	return fmt.Sprintf("mdsite-embed-%d-", n)
+}
+
+// insert replaces the embed placeholders in rendered HTML with the content of
+// the embedded pages.
+func (w *wikiPage) insert(content []byte) []byte {
+	for n, embed := range w.embeds {
+		block := []byte(fmt.Sprintf(`<div class="%s">%s</div>`, EmbedClass, embed))
+		placeholder := []byte(w.placeholder(n))
+
+		// Embeds on a line of their own replace the whole paragraph
+		content = bytes.Replace(content, []byte("<p>"+string(placeholder)+"</p>"), block, 1)
+		content = bytes.Replace(content, placeholder, block, 1)
+	}
+
+	return content
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"testing"
+)
+
+// testWiki resolves wiki links from a fixed set of targets.
+type testWiki map[string]*WikiTarget
+
+func (w testWiki) ResolveWiki(name string) *WikiTarget {
+	return w[name]
+}
+
+type WikiSuite struct {
+	suite.Suite
+	wiki testWiki
+}
+
+func TestWikiSuite(t *testing.T) {
+	suite.Run(t, new(WikiSuite))
+}
+
+func (s *WikiSuite) SetupTest() {
+	s.wiki = testWiki{
+		"Guide":   {Url: "/guide", Page: true},
+		"pic.png": {Url: "/img/pic.png"},
+		"Snippet": {Url: "/snippet", Page: true, Embed: func(w io.Writer) error {
+			_, err := io.WriteString(w, "<p>Embedded</p>")
+			return err
+		}},
+		"Broken": {Url: "/broken", Page: true, Embed: func(w io.Writer) error {
+			return errors.New("failed")
+		}},
+	}
+}
+
+func (s *WikiSuite) convert(md string) string {
+	w := &wikiPage{wiki: s.wiki}
+	return string(w.convert([]byte(md)))
+}
+
+func (s *WikiSuite) TestLinks() {
+	cases := map[string]string{
+		"[[Guide]]":                 `<a href="/guide" class="mdsite-wikilink">Guide</a>`,
+		"[[ Guide | the guide ]]":   `<a href="/guide" class="mdsite-wikilink">the guide</a>`,
+		"[[Guide#Set Up]]":          `<a href="/guide#set-up" class="mdsite-wikilink">Guide#Set Up</a>`,
+		"[[#Local Heading|here]]":   `<a href="#local-heading" class="mdsite-wikilink">here</a>`,
+		"![[pic.png|A picture]]":    `<img class="mdsite-embed" src="/img/pic.png" alt="A picture"/>`,
+		"![[Guide]]":                `<a href="/guide" class="mdsite-wikilink">Guide</a>`,
+		"![[Broken]]":               `<a href="/broken" class="mdsite-wikilink">Broken</a>`,
+		"[[Missing <b>]]":           `<a class="mdsite-wikilink mdsite-new-page" title="New page: Missing &lt;b&gt;" style="color: #999; font-style: italic">Missing &lt;b&gt;</a>`,
+		"`[[Guide]]` and ``[[x]]``": "`[[Guide]]` and ``[[x]]``",
+		"`unclosed [[Guide]]":       "`unclosed " + `<a href="/guide" class="mdsite-wikilink">Guide</a>`,
+	}
+
+	for md, html := range cases {
+		s.Equal(html, s.convert(md), md)
+	}
+}
+
+func (s *WikiSuite) TestFencedCode() {
+	md := "```\n[[Guide]]\n```\n[[Guide]]\n"
+
+	s.Equal("```\n[[Guide]]\n```\n"+`<a href="/guide" class="mdsite-wikilink">Guide</a>`+"\n", s.convert(md))
+}
+
+func (s *WikiSuite) TestEmbed() {
+	w := &wikiPage{wiki: s.wiki}
+	md := w.convert([]byte("![[Snippet]]\n\nText with ![[Snippet]] inline\n"))
+	s.Equal("mdsite-embed-0-\n\nText with mdsite-embed-1- inline\n", string(md))
+
+	html := w.insert([]byte("<p>mdsite-embed-0-</p>\n<p>Text with mdsite-embed-1- inline</p>"))
+	s.Equal(`<div class="mdsite-embed"><p>Embedded</p></div>`+"\n"+
+		`<p>Text with <div class="mdsite-embed"><p>Embedded</p></div> inline</p>`, string(html))
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/json"
+	"github.com/apex/log/handlers/logfmt"
+	"github.com/apex/log/handlers/text"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io"
+	"sync"
+	"time"
+)
+
+// SetupLogging sends application logs to the same output as the access log,
+// so the server writes a single stream.
+func SetupLogging(v *config.Values) {
+	log.SetHandler(text.New(v.Logs()))
+}
+
+// AccessLog records each request through a dedicated apex logger, in one of
+// the config.AccessLog* formats. Fields only apply to json and logfmt, since
+// the combined format is fixed. It should write to the same output as the
+// application log, as set up by SetupLogging.
+func AccessLog(format string, fields []string, w io.Writer) RequestObserver {
+	logger := &log.Logger{Level: log.InfoLevel}
+
+	switch format {
+	case config.AccessLogJson:
+		logger.Handler = json.New(w)
+	case config.AccessLogCombined:
+		logger.Handler = &combinedHandler{w: w}
+		fields = append([]string{"proto"}, config.AccessLogFieldNames...)
+	default:
+		logger.Handler = logfmt.New(w)
+	}
+
+	return func(c *gin.Context, rec *RequestRecord) {
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+
+		values := map[string]interface{}{
+			"id":      rec.Id,
+			"client":  c.ClientIP(),
+			"host":    c.Request.Host,
+			"method":  c.Request.Method,
+			"uri":     c.Request.RequestURI,
+			"proto":   c.Request.Proto,
+			"user":    rec.User,
+			"page":    rec.Page,
+			"mode":    rec.Mode,
+			"status":  c.Writer.Status(),
+			"bytes":   size,
+			"latency": float64(rec.Latency) / float64(time.Millisecond),
+			"referer": c.Request.Referer(),
+			"agent":   c.Request.UserAgent(),
+		}
+
+		entry := log.Fields{}
+		for _, f := range fields {
+			entry[f] = values[f]
+		}
+
+		logger.WithFields(entry).Info("request")
+	}
+}
+
+// combinedHandler writes entries in the Apache combined log format.
+type combinedHandler struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (h *combinedHandler) HandleLog(e *log.Entry) error {
+	f := e.Fields
+
+	dash := func(name string) interface{} {
+		v := f.Get(name)
+		if v == nil || v == "" || v == 0 {
+			return "-"
+		}
+		return v
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := fmt.Fprintf(h.w, "%s - %s [%s] \"%s %s %s\" %d %v %q %q\n",
+		dash("client"), dash("user"), e.Timestamp.Format("02/Jan/2006:15:04:05 -0700"),
+		f.Get("method"), f.Get("uri"), f.Get("proto"),
+		f.Get("status"), dash("bytes"), dash("referer"), dash("agent"))
+
+	return err
+}
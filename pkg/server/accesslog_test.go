@@ -0,0 +1,135 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type AccessLogTestSuite struct {
+	suite.Suite
+	site *Site
+}
+
+func TestAccessLogTestSuite(t *testing.T) {
+	suite.Run(t, new(AccessLogTestSuite))
+}
+
+func (t *AccessLogTestSuite) SetupSuite() {
+	conf := testSiteValues(&t.Suite, "auth01")
+	t.site = loadTestSite(&t.Suite, conf)
+}
+
+func (t *AccessLogTestSuite) serve(format string, fields []string, req *http.Request) (*httptest.ResponseRecorder, string) {
+	buf := &bytes.Buffer{}
+
+	e := gin.New()
+	e.Use(TrackRequests(AccessLog(format, fields, buf)))
+	e.NoRoute(gin.WrapH(t.site.Handler()))
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	return w, buf.String()
+}
+
+func (t *AccessLogTestSuite) TestJson() {
+	req := httptest.NewRequest("GET", "/security/incidents", nil)
+	req.SetBasicAuth("alice", "password")
+	req.Header.Set(RequestIdHeader, "req-1234")
+
+	w, out := t.serve(config.AccessLogJson, config.DefaultAccessLogFields, req)
+	t.Equal(http.StatusOK, w.Code)
+	t.Equal("req-1234", w.Header().Get(RequestIdHeader))
+
+	entry := struct {
+		Message string                 `json:"message"`
+		Fields  map[string]interface{} `json:"fields"`
+	}{}
+	t.Require().NoError(json.Unmarshal([]byte(out), &entry))
+
+	t.Equal("request", entry.Message)
+	t.Equal("req-1234", entry.Fields["id"])
+	t.Equal("alice", entry.Fields["user"])
+	t.Equal("security/incidents.md", entry.Fields["page"])
+	t.Equal("Markdown", entry.Fields["mode"])
+	t.Equal(float64(http.StatusOK), entry.Fields["status"])
+	t.Equal(float64(w.Body.Len()), entry.Fields["bytes"])
+	t.Contains(entry.Fields, "latency")
+	t.NotContains(entry.Fields, "agent")
+}
+
+func (t *AccessLogTestSuite) TestLogfmt_Fields() {
+	req := httptest.NewRequest("GET", "/page", nil)
+
+	w, out := t.serve(config.AccessLogLogfmt, []string{"id", "status"}, req)
+	t.Equal(http.StatusUnauthorized, w.Code)
+
+	t.Contains(out, "message=request")
+	t.Contains(out, "status=401")
+	t.Contains(out, "id="+w.Header().Get(RequestIdHeader))
+	t.NotContains(out, "uri=")
+	t.NotEmpty(w.Header().Get(RequestIdHeader))
+}
+
+func (t *AccessLogTestSuite) TestCombined() {
+	req := httptest.NewRequest("GET", "/public/open", nil)
+	req.Header.Set("User-Agent", "test-agent")
+
+	w, out := t.serve(config.AccessLogCombined, nil, req)
+	t.Equal(http.StatusOK, w.Code)
+
+	t.True(strings.HasPrefix(out, "192.0.2.1 - - ["), out)
+	t.Contains(out, `] "GET /public/open HTTP/1.1" 200 `)
+	t.True(strings.HasSuffix(out, "\"-\" \"test-agent\"\n"), out)
+}
+
+func (t *AccessLogTestSuite) TestRequestId_Invalid() {
+	t.False(validRequestId(""))
+	t.False(validRequestId("has space"))
+	t.False(validRequestId(strings.Repeat("x", 65)))
+	t.True(validRequestId("abc-123"))
+}
+
+func (t *AccessLogTestSuite) TestSharedOutput() {
+	logger := log.Log.(*log.Logger)
+	defer func(h log.Handler) { logger.Handler = h }(logger.Handler)
+
+	buf := &bytes.Buffer{}
+	conf := testSiteValues(&t.Suite, "auth01")
+	conf.LogWriter = buf
+	SetupLogging(conf)
+
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+
+	req := httptest.NewRequest("GET", "/ping", nil)
+	d.engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	t.Contains(buf.String(), "Loading site config")
+	t.Contains(buf.String(), "message=request")
+	t.Contains(buf.String(), "uri=/ping")
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const AdminPath = "/_mdsite/admin"
+
+type AdminResult struct {
+	Name   string           `json:"name"`
+	Status site.IndexStatus `json:"index"`
+	Error  string           `json:"error,omitempty"`
+}
+
+type AdminStatus struct {
+	Version string             `json:"version"`
+	Started time.Time          `json:"started"`
+	Uptime  float64            `json:"uptimeSeconds"`
+	Config  *config.Values     `json:"config"`
+	Sites   []AdminSiteDetails `json:"sites"`
+}
+
+type AdminSiteDetails struct {
+	Name   string            `json:"name"`
+	Hosts  []string          `json:"hosts"`
+	Index  site.IndexStatus  `json:"index"`
+	Config *config.Values    `json:"config,omitempty"`
+	Pages  []*site.PageEntry `json:"pages,omitempty"`
+}
+
+// attachAdmin mounts the admin API, on the admin listener if there is one.
+// The API is disabled unless a token is configured.
+func (d *Dispatcher) attachAdmin() {
+	if d.conf.AdminToken == "" {
+		return
+	}
+
+	var g *gin.RouterGroup
+	if d.admin != nil {
+		g = d.admin.Group(AdminPath, d.requireAdminToken)
+	} else {
+		g = d.engine.Group(d.conf.BaseUrl()+AdminPath, d.requireAdminToken)
+	}
+
+	g.POST("/reindex", d.adminReindex)
+	g.POST("/reload-config", d.adminReloadConfig)
+	g.GET("/status", d.adminStatus)
+	g.GET("/index", d.adminIndex)
+}
+
+func (d *Dispatcher) requireAdminToken(c *gin.Context) {
+	authz := c.GetHeader("Authorization")
+	token := ""
+	if len(authz) > 7 && strings.EqualFold(authz[:7], "Bearer ") {
+		token = strings.TrimSpace(authz[7:])
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(d.conf.AdminToken)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="mdsite-admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "a valid admin token is required"})
+		return
+	}
+}
+
+// adminSites selects the sites named by the "site" query parameter, or every
+// site if there is none.
+func (d *Dispatcher) adminSites(c *gin.Context) ([]*HostedSite, bool) {
+	name := c.Query("site")
+	if name == "" {
+		return d.Sites(), true
+	}
+
+	hs := d.FindSite(name)
+	if hs == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown site: %s", name)})
+		return nil, false
+	}
+
+	return []*HostedSite{hs}, true
+}
+
+func (d *Dispatcher) adminReindex(c *gin.Context) {
+	sites, ok := d.adminSites(c)
+	if !ok {
+		return
+	}
+
+	d.respondAdmin(c, sites, func(hs *HostedSite) error {
+		log.Infof("Reindexing site [%s] on admin request", hs.Name)
+		_, err := hs.Site.ReIndex()
+		return err
+	})
+}
+
+func (d *Dispatcher) adminReloadConfig(c *gin.Context) {
+	sites, ok := d.adminSites(c)
+	if !ok {
+		return
+	}
+
+	d.respondAdmin(c, sites, d.ReloadSite)
+}
+
+// respondAdmin applies an action to each site, and reports the outcome. Any
+// failure is reported with a server error status.
+func (d *Dispatcher) respondAdmin(c *gin.Context, sites []*HostedSite, action func(hs *HostedSite) error) {
+	code := http.StatusOK
+	results := make([]AdminResult, 0, len(sites))
+
+	for _, hs := range sites {
+		r := AdminResult{Name: hs.Name}
+
+		if err := action(hs); err != nil {
+			r.Error = err.Error()
+			code = http.StatusInternalServerError
+		}
+
+		// Report on the version of the site now being served
+		if current := d.FindSite(hs.Name); current != nil {
+			r.Status = current.Site.IndexStatus()
+		}
+
+		results = append(results, r)
+	}
+
+	c.JSON(code, gin.H{"sites": results})
+}
+
+// ReloadSite reloads the config of a hosted site, and swaps in the new
+// version once it has been indexed. On failure the current version is kept.
+func (d *Dispatcher) ReloadSite(hs *HostedSite) error {
+	log.Infof("Reloading config for site [%s]", hs.Name)
+
+	ns, err := hs.Site.Reload()
+	if err != nil {
+		log.Errorf("Failed to reload site [%s], keeping the current config: %s", hs.Name, err)
+		return err
+	}
+
+	if !d.replaceSite(hs, ns) {
+		return fmt.Errorf("site %s was replaced during the reload", hs.Name)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) adminStatus(c *gin.Context) {
+	status := AdminStatus{
+		Version: config.Version,
+		Started: d.started,
+		Uptime:  time.Since(d.started).Seconds(),
+		Config:  d.conf.Redacted(),
+	}
+
+	for _, hs := range d.Sites() {
+		status.Sites = append(status.Sites, AdminSiteDetails{
+			Name:   hs.Name,
+			Hosts:  hs.Hosts,
+			Index:  hs.Site.IndexStatus(),
+			Config: hs.Site.Config().Redacted(),
+		})
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (d *Dispatcher) adminIndex(c *gin.Context) {
+	sites, ok := d.adminSites(c)
+	if !ok {
+		return
+	}
+
+	details := make([]AdminSiteDetails, 0, len(sites))
+	for _, hs := range sites {
+		details = append(details, AdminSiteDetails{
+			Name:  hs.Name,
+			Hosts: hs.Hosts,
+			Index: hs.Site.IndexStatus(),
+			Pages: hs.Site.Index().Pages,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sites": details})
+}
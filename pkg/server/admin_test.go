@@ -0,0 +1,195 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testAdminToken = "s3cret-admin-token"
+
+type AdminTestSuite struct {
+	suite.Suite
+	tempDir    string
+	dispatcher *Dispatcher
+	testServer *httptest.Server
+}
+
+func TestAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(AdminTestSuite))
+}
+
+// copyTree copies a test site, so that tests can change it.
+func copyTree(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, 0644)
+	})
+}
+
+func (t *AdminTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "mdsite-admin")
+	t.Require().NoError(err)
+	t.tempDir = dir
+
+	conf := testSiteValues(&t.Suite, "test01")
+	t.Require().NoError(copyTree(filepath.Dir(conf.SitePath), dir))
+	conf.SitePath = filepath.Join(dir, "site")
+	conf.ConfigPath = filepath.Join(dir, "config")
+	conf.AdminToken = testAdminToken
+
+	t.dispatcher = CreateDispatcher(conf)
+	st := loadTestSite(&t.Suite, conf)
+	st.Index()
+	t.dispatcher.AttachSite(st)
+
+	t.testServer = httptest.NewServer(t.dispatcher.engine)
+}
+
+func (t *AdminTestSuite) TearDownTest() {
+	t.testServer.Close()
+	os.RemoveAll(t.tempDir)
+}
+
+func (t *AdminTestSuite) admin() *httpexpect.Expect {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	return e.Builder(func(r *httpexpect.Request) {
+		r.WithHeader("Authorization", "Bearer "+testAdminToken)
+	})
+}
+
+func (t *AdminTestSuite) TestToken() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET(AdminPath + "/status").Expect().Status(http.StatusUnauthorized)
+	e.GET(AdminPath+"/status").WithHeader("Authorization", "Bearer wrong").Expect().Status(http.StatusUnauthorized)
+	e.POST(AdminPath + "/reindex").Expect().Status(http.StatusUnauthorized)
+
+	t.admin().GET(AdminPath + "/status").Expect().Status(http.StatusOK)
+}
+
+func (t *AdminTestSuite) TestDisabledWithoutToken() {
+	conf := testSiteValues(&t.Suite, "test01")
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+
+	server := httptest.NewServer(d.engine)
+	defer server.Close()
+
+	httpexpect.New(t.T(), server.URL).GET(AdminPath+"/status").
+		WithHeader("Authorization", "Bearer ").
+		Expect().Status(http.StatusNotFound)
+}
+
+func (t *AdminTestSuite) TestStatus() {
+	status := t.admin().GET(AdminPath + "/status").Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	status.Value("version").String().NotEmpty()
+	status.Path("$.config.AdminToken").Equal("[redacted]")
+	status.Path("$.sites[0].name").Equal("default")
+	status.Path("$.sites[0].index.pages").Equal(4)
+	status.Path("$.sites[0].config.SiteConfig.Title").Equal("Test01")
+	t.NotContains(status.Raw(), testAdminToken)
+}
+
+func (t *AdminTestSuite) TestIndexDump() {
+	dump := t.admin().GET(AdminPath + "/index").Expect().
+		Status(http.StatusOK).
+		JSON()
+
+	dump.Path("$.sites[0].pages").Array().Length().Equal(4)
+	dump.Path("$.sites[0].pages[0].Path").String().NotEmpty()
+
+	t.admin().GET(AdminPath+"/index").WithQuery("site", "nope").Expect().
+		Status(http.StatusNotFound)
+}
+
+func (t *AdminTestSuite) TestReindex() {
+	err := ioutil.WriteFile(filepath.Join(t.tempDir, "site", "added.md"), []byte("# Added"), 0644)
+	t.Require().NoError(err)
+
+	t.admin().POST(AdminPath + "/reindex").Expect().
+		Status(http.StatusOK).
+		JSON().Path("$.sites[0].index.pages").Equal(5)
+
+	httpexpect.New(t.T(), t.testServer.URL).GET("/added").Expect().Status(http.StatusOK)
+}
+
+func (t *AdminTestSuite) TestReindex_Failure() {
+	t.Require().NoError(os.RemoveAll(filepath.Join(t.tempDir, "site")))
+
+	result := t.admin().POST(AdminPath + "/reindex").Expect().
+		Status(http.StatusInternalServerError).
+		JSON()
+	result.Path("$.sites[0].error").String().NotEmpty()
+	result.Path("$.sites[0].index.pages").Equal(4)
+}
+
+func (t *AdminTestSuite) TestReloadConfig() {
+	siteFile := filepath.Join(t.tempDir, "config", "site.yml")
+	err := ioutil.WriteFile(siteFile, []byte("title: Reloaded\n"), 0644)
+	t.Require().NoError(err)
+
+	old := t.dispatcher.FindSite("default").Site
+
+	t.admin().POST(AdminPath + "/reload-config").Expect().
+		Status(http.StatusOK).
+		JSON().Path("$.sites[0].index.pages").Equal(4)
+
+	current := t.dispatcher.FindSite("default").Site
+	t.True(old != current)
+	t.Equal("Reloaded", current.Config().SiteConfig.Title)
+	t.Equal("Test01", old.Config().SiteConfig.Title)
+}
+
+func (t *AdminTestSuite) TestReloadConfig_Failure() {
+	siteFile := filepath.Join(t.tempDir, "config", "site.yml")
+	err := ioutil.WriteFile(siteFile, []byte("global:\n  pageTemplate: \"{{.Broken\"\n"), 0644)
+	t.Require().NoError(err)
+
+	old := t.dispatcher.FindSite("default").Site
+
+	t.admin().POST(AdminPath + "/reload-config").Expect().
+		Status(http.StatusInternalServerError).
+		JSON().Path("$.sites[0].error").String().Contains("global.pageTemplate")
+
+	t.True(old == t.dispatcher.FindSite("default").Site)
+	httpexpect.New(t.T(), t.testServer.URL).GET("/sample-01").Expect().Status(http.StatusOK)
+}
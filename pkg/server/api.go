@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const ApiPath = site.ApiPath
+
+// PageRef is the summary of a page returned by the API.
+type PageRef struct {
+	Url   string `json:"url"`
+	Path  string `json:"path"`
+	Label string `json:"label"`
+}
+
+type BacklinksResponse struct {
+	Page      PageRef   `json:"page"`
+	Backlinks []PageRef `json:"backlinks"`
+}
+
+type ApiError struct {
+	Error string `json:"error"`
+}
+
+func NewPageRef(p *site.PageEntry) PageRef {
+	return PageRef{
+		Url:   p.Url,
+		Path:  filepath.ToSlash(p.Path),
+		Label: p.Label,
+	}
+}
+
+func NewPageRefs(pages []*site.PageEntry) []PageRef {
+	refs := make([]PageRef, len(pages))
+	for n, p := range pages {
+		refs[n] = NewPageRef(p)
+	}
+
+	return refs
+}
+
+func AttachApi(r gin.IRoutes) {
+	r.GET(ApiPath+"/pages/*page", PageApi)
+	r.GET(ApiPath+"/graph", GraphApi)
+	r.GET(ApiPath+"/orphans", OrphansApi)
+}
+
+// GraphApi serves the link graph of the pages the user can read.
+func GraphApi(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassApi)
+
+	c.JSON(http.StatusOK, s.RenderedPages(s.VisibleIndex(c)).Graph(s.conf.SiteConfig.Title))
+}
+
+// OrphansApi lists the pages that nothing links to, and that are missing
+// from the page order.
+func OrphansApi(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassApi)
+
+	c.JSON(http.StatusOK, NewPageRefs(s.RenderedPages(s.VisibleIndex(c)).Orphans()))
+}
+
+// PageApi serves the details of single pages, at /api/pages/<page>/<detail>.
+// Pages are given by their path within the site.
+func PageApi(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassApi)
+
+	route, detail := splitDetail(c.Param("page"))
+	i := s.VisibleIndex(c)
+
+	p, ok := i.PageLookup[s.conf.SiteUrl(route)]
+	if !ok {
+		c.JSON(http.StatusNotFound, ApiError{Error: "no such page: " + route})
+		return
+	}
+
+	switch detail {
+	case "backlinks":
+		c.JSON(http.StatusOK, BacklinksResponse{
+			Page:      NewPageRef(p),
+			Backlinks: NewPageRefs(i.BacklinksTo(p)),
+		})
+	default:
+		c.JSON(http.StatusNotFound, ApiError{Error: "no such page detail: " + detail})
+	}
+}
+
+// splitDetail splits the last segment from a page path.
+func splitDetail(p string) (string, string) {
+	p = strings.TrimSuffix(p, "/")
+
+	n := strings.LastIndex(p, "/")
+	if n < 0 {
+		return "/", p
+	}
+
+	route := p[:n]
+	if route == "" {
+		route = "/"
+	}
+
+	return route, p[n+1:]
+}
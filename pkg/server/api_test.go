@@ -0,0 +1,82 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type ApiTestSuite struct {
+	suite.Suite
+}
+
+func TestApiTestSuite(t *testing.T) {
+	suite.Run(t, new(ApiTestSuite))
+}
+
+func (t *ApiTestSuite) serve(siteName string) (*httpexpect.Expect, func()) {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, siteName))
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	server := httptest.NewServer(st.Handler())
+
+	return httpexpect.New(t.T(), server.URL), server.Close
+}
+
+func (t *ApiTestSuite) TestGraph() {
+	e, done := t.serve("graph01")
+	defer done()
+
+	graph := e.GET(ApiPath + "/graph").Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	graph.ValueEqual("title", "Graph01")
+	graph.Path("$.nodes[*].id").Array().Equal([]string{"/intro", "/ops/runbook", "/ops/unlinked"})
+	graph.Path("$.nodes[1].section").Equal("ops")
+	graph.Path("$.nodes[1].tags").Equal([]string{"ops", "oncall"})
+	graph.Path("$.nodes[0].inbound").Equal(1)
+	graph.Value("edges").Array().Equal([]map[string]string{
+		{"source": "/intro", "target": "/ops/runbook"},
+		{"source": "/ops/runbook", "target": "/intro"},
+	})
+}
+
+func (t *ApiTestSuite) TestOrphans() {
+	e, done := t.serve("graph01")
+	defer done()
+
+	e.GET(ApiPath + "/orphans").Expect().
+		Status(http.StatusOK).
+		JSON().Path("$[*].url").Array().Equal([]string{"/ops/unlinked"})
+}
+
+func (t *ApiTestSuite) TestFrontMatterHidden() {
+	e, done := t.serve("graph01")
+	defer done()
+
+	e.GET("/intro").Expect().
+		Status(http.StatusOK).
+		Body().
+		NotContains("tags").
+		Contains(`<h1 id="intro">Intro</h1>`)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/auth"
+	"github.com/zpxio/mdsite/pkg/site"
+	"github.com/zpxio/mdsite/pkg/util"
+	"net/http"
+	"strings"
+)
+
+func (s *Site) loadAuth() error {
+	sa, err := auth.LoadSiteAuth(s.conf)
+	if err != nil {
+		return err
+	}
+	s.htpasswd, s.jwt, s.acl = sa.Htpasswd, sa.Jwt, sa.Acl
+
+	return nil
+}
+
+// bearerToken finds a token in the Authorization header, or failing that in
+// the configured cookie.
+func (s *Site) bearerToken(c *gin.Context) string {
+	authz := c.GetHeader("Authorization")
+	if len(authz) > 7 && strings.EqualFold(authz[:7], "Bearer ") {
+		return strings.TrimSpace(authz[7:])
+	}
+
+	cookie := s.conf.SiteConfig.Auth.Jwt.Cookie
+	if cookie != "" {
+		token, err := c.Cookie(cookie)
+		if err == nil {
+			return token
+		}
+	}
+
+	return ""
+}
+
+func (s *Site) authExempt(sitePath string) bool {
+	for _, pattern := range s.conf.SiteConfig.Auth.Exempt {
+		if util.PathMatches(pattern, sitePath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authenticate checks the credentials on a request for a site path, and
+// attaches the user to the context. Requests without valid credentials are
+// rejected.
+func (s *Site) authenticate(c *gin.Context, sitePath string) {
+	if !s.conf.SiteConfig.Auth.Enabled() || s.authExempt(sitePath) {
+		return
+	}
+
+	realm := s.conf.SiteConfig.Auth.Realm
+
+	if s.jwt != nil {
+		if token := s.bearerToken(c); token != "" {
+			u, err := s.jwt.Verify(token)
+			if err == nil {
+				noteUser(c, u)
+				return
+			}
+
+			log.Infof("Rejected bearer token: %s", err)
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="invalid_token"`, realm))
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if s.htpasswd != nil {
+		user, pass, ok := c.Request.BasicAuth()
+		if ok && s.htpasswd.Authenticate(user, pass) {
+			noteUser(c, &auth.User{Name: user})
+			return
+		}
+
+		c.Writer.Header().Add("WWW-Authenticate", fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm))
+	}
+	if s.jwt != nil {
+		c.Writer.Header().Add("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q`, realm))
+	}
+
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+func (s *Site) Authenticate(c *gin.Context) {
+	s.authenticate(c, site.StripBasePath(s.conf.BaseUrl(), c.Request.URL.Path))
+}
+
+// Authenticate applies the authentication of the site serving the request
+// to the dispatcher's own routes.
+func (d *Dispatcher) Authenticate(c *gin.Context) {
+	hs := d.SelectSite(c.Request)
+	if hs == nil {
+		return
+	}
+
+	hs.Site.authenticate(c, site.StripBasePath(d.conf.BaseUrl(), c.Request.URL.Path))
+}
+
+// CanRead checks whether the user on a request may read a site path.
+func (s *Site) CanRead(c *gin.Context, sitePath string) bool {
+	return s.acl.Allowed(auth.ContextUser(c), sitePath)
+}
+
+// Authorize rejects requests for site paths the user may not read.
+func (s *Site) Authorize(c *gin.Context) {
+	sitePath := site.StripBasePath(s.conf.BaseUrl(), c.Request.URL.Path)
+	if s.CanRead(c, sitePath) {
+		return
+	}
+
+	c.AbortWithStatus(http.StatusForbidden)
+}
+
+// VisibleIndex returns the site index, limited to the pages the user on the
+// request may read.
+func (s *Site) VisibleIndex(c *gin.Context) *site.PageIndex {
+	return s.indexFor(auth.ContextUser(c))
+}
+
+// PublicIndex returns the site index, limited to the pages anyone may read
+// without signing in.
+func (s *Site) PublicIndex() *site.PageIndex {
+	return s.indexFor(nil)
+}
+
+func (s *Site) indexFor(u *auth.User) *site.PageIndex {
+	i := s.Index()
+	if s.acl == nil {
+		return i
+	}
+
+	return i.Filter(func(p *site.PageEntry) bool {
+		return s.acl.Allowed(u, p.Route)
+	})
+}
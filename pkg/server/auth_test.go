@@ -0,0 +1,257 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	testServer *httptest.Server
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
+
+func (t *AuthTestSuite) SetupSuite() {
+	conf := testSiteValues(&t.Suite, "auth01")
+
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+
+	t.testServer = httptest.NewServer(d.engine)
+}
+
+func (t *AuthTestSuite) TearDownSuite() {
+	t.testServer.Close()
+}
+
+func (t *AuthTestSuite) TestBasicAuth_Required() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/page").Expect().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Contains(`realm="Auth Test"`)
+
+	e.GET("/page").WithBasicAuth("alice", "wrong").Expect().Status(http.StatusUnauthorized)
+	e.GET("/page").WithBasicAuth("mallory", "password").Expect().Status(http.StatusUnauthorized)
+}
+
+func (t *AuthTestSuite) TestBasicAuth_UserInTemplate() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/page").WithBasicAuth("alice", "password").Expect().
+		Status(http.StatusOK).
+		Body().Contains("Signed in as alice")
+}
+
+func (t *AuthTestSuite) TestAcl_Page() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/security/incidents").WithBasicAuth("alice", "password").Expect().Status(http.StatusOK)
+	e.GET("/security/incidents").WithBasicAuth("bob", "password").Expect().Status(http.StatusForbidden)
+	e.GET("/public/../security/incidents").WithBasicAuth("bob", "password").Expect().Status(http.StatusForbidden)
+	e.GET("/public/../security/incidents").Expect().Status(http.StatusUnauthorized)
+}
+
+func (t *AuthTestSuite) TestAcl_Toc() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/toc").WithBasicAuth("alice", "password").Expect().
+		Status(http.StatusOK).Body().Contains("/security/incidents")
+	e.GET("/toc").WithBasicAuth("bob", "password").Expect().
+		Status(http.StatusOK).Body().NotContains("/security/incidents")
+}
+
+func (t *AuthTestSuite) TestAcl_WikiEmbed() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	body := e.GET("/digest").WithBasicAuth("alice", "password").Expect().
+		Status(http.StatusOK).Body()
+	body.Contains("Restricted.")
+	body.Contains(`href="/security/incidents"`)
+
+	// Pages the user can't read are neither embedded nor linked to
+	for _, req := range []*httpexpect.Request{
+		e.GET("/digest").WithBasicAuth("bob", "password"),
+		e.GET("/public/digest"),
+	} {
+		body := req.Expect().Status(http.StatusOK).Body()
+		body.NotContains("Restricted.")
+		body.NotContains("Incident Response")
+		body.NotContains(`href="/security/incidents"`)
+		body.Contains("mdsite-new-page")
+	}
+}
+
+func (t *AuthTestSuite) TestBasicAuth_Exempt() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/ping").Expect().Status(http.StatusOK)
+	e.GET("/public/open").Expect().Status(http.StatusOK)
+	e.GET("/toc").Expect().Status(http.StatusUnauthorized)
+}
+
+func (t *AuthTestSuite) TestBasicAuth_Traversal() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/public/../page").Expect().Status(http.StatusUnauthorized)
+	e.GET("/public/./open").Expect().Status(http.StatusOK)
+
+	// Encoded dot segments are sent as they are
+	resp, err := http.Get(t.testServer.URL + "/public/%2e%2e/page")
+	t.Require().NoError(err)
+	resp.Body.Close()
+	t.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+type JwtAuthTestSuite struct {
+	suite.Suite
+	tempDir    string
+	key        *ecdsa.PrivateKey
+	testServer *httptest.Server
+}
+
+func TestJwtAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(JwtAuthTestSuite))
+}
+
+const jwtSiteConfig = `---
+title: Jwt
+global:
+  pageTemplate: >-
+    <main>{{.User.Name}}{{range .User.Groups}}[{{.}}]{{end}}{{.Content}}</main>
+auth:
+  jwt:
+    keys:
+      - signing.pem
+    issuer: https://sso.example.com
+    audience: mdsite
+    cookie: sso_token
+    groupsClaim: roles
+`
+
+func (t *JwtAuthTestSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "mdsite-jwt")
+	t.Require().NoError(err)
+	t.tempDir = dir
+
+	t.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	t.Require().NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(&t.key.PublicKey)
+	t.Require().NoError(err)
+
+	configDir := filepath.Join(dir, "config")
+	siteDir := filepath.Join(dir, "site")
+	t.Require().NoError(os.MkdirAll(configDir, 0755))
+	t.Require().NoError(os.MkdirAll(siteDir, 0755))
+	t.Require().NoError(ioutil.WriteFile(filepath.Join(configDir, "site.yml"), []byte(jwtSiteConfig), 0600))
+	t.Require().NoError(ioutil.WriteFile(filepath.Join(configDir, "signing.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	t.Require().NoError(ioutil.WriteFile(filepath.Join(siteDir, "page.md"), []byte("# Page\n"), 0600))
+
+	conf := config.Create()
+	conf.EnableTestMode()
+	conf.ConfigPath = configDir
+	conf.SitePath = siteDir
+
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+
+	t.testServer = httptest.NewServer(d.engine)
+}
+
+func (t *JwtAuthTestSuite) TearDownSuite() {
+	t.testServer.Close()
+	os.RemoveAll(t.tempDir)
+}
+
+func (t *JwtAuthTestSuite) token(claims map[string]interface{}) string {
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(body)
+
+	h := crypto.SHA256.New()
+	h.Write([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, t.key, h.Sum(nil))
+	t.Require().NoError(err)
+
+	sig := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rb):32], rb)
+	copy(sig[64-len(sb):], sb)
+
+	return signed + "." + enc.EncodeToString(sig)
+}
+
+func (t *JwtAuthTestSuite) validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "bob",
+		"iss":   "https://sso.example.com",
+		"aud":   "mdsite",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"eng", "sre"},
+	}
+}
+
+func (t *JwtAuthTestSuite) TestBearer() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/page").WithHeader("Authorization", "Bearer "+t.token(t.validClaims())).
+		Expect().Status(http.StatusOK).
+		Body().Contains("bob[eng][sre]")
+}
+
+func (t *JwtAuthTestSuite) TestCookie() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/page").WithCookie("sso_token", t.token(t.validClaims())).
+		Expect().Status(http.StatusOK).
+		Body().Contains("bob[eng][sre]")
+}
+
+func (t *JwtAuthTestSuite) TestRejected() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/page").Expect().Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Contains("Bearer")
+
+	claims := t.validClaims()
+	claims["aud"] = "elsewhere"
+	e.GET("/page").WithHeader("Authorization", "Bearer "+t.token(claims)).
+		Expect().Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Contains("invalid_token")
+}
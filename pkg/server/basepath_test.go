@@ -0,0 +1,73 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type BasePathTestSuite struct {
+	suite.Suite
+	testServer *httptest.Server
+}
+
+func (t *BasePathTestSuite) SetupSuite() {
+	conf := testSiteValues(&t.Suite, "test01")
+	conf.BasePath = "/docs/"
+
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+
+	t.testServer = httptest.NewServer(d.engine)
+}
+
+func (t *BasePathTestSuite) TearDownSuite() {
+	t.testServer.Close()
+}
+
+func (t *BasePathTestSuite) TestPing_UnderPrefix() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/docs/ping").Expect().Status(http.StatusOK)
+}
+
+func (t *BasePathTestSuite) TestIndex_UnderPrefix() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/docs/").Expect().Status(http.StatusOK)
+}
+
+func (t *BasePathTestSuite) TestToc_PrefixedLinks() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/docs/toc").Expect().Status(http.StatusOK).Body().Contains(`href="/docs/sample-01"`)
+}
+
+func (t *BasePathTestSuite) TestPage_UnderPrefix() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/docs/sample-01").Expect().Status(http.StatusOK)
+	e.GET("/sample-01").Expect().Status(http.StatusNotFound)
+}
+
+func TestBasePathTestSuite(t *testing.T) {
+	suite.Run(t, new(BasePathTestSuite))
+}
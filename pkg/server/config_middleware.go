@@ -23,6 +23,7 @@ import (
 )
 
 const contextConfig = "mdsite-config"
+const contextDispatcher = "mdsite-dispatcher"
 
 func AddContextConfiguration(conf *config.Values) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -42,6 +43,20 @@ func ContextConfig(c *gin.Context) *config.Values {
 	return nil
 }
 
+func AddContextDispatcher(d *Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextDispatcher, d)
+	}
+}
+
+// ContextDispatcher returns the dispatcher handling the request, or nil if
+// the request is being handled by a standalone site.
+func ContextDispatcher(c *gin.Context) *Dispatcher {
+	d, _ := c.Value(contextDispatcher).(*Dispatcher)
+
+	return d
+}
+
 func SiteBaseDirectory(c *gin.Context) string {
 	conf := ContextConfig(c)
 
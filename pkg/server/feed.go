@@ -0,0 +1,209 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/resource"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const FeedPath = site.FeedPath
+
+// The longest summary made from the text of a page
+const feedSummaryLength = 300
+
+var feedMediaTypes = map[string]string{
+	site.FeedAtom: "application/atom+xml; charset=utf-8",
+	site.FeedRss:  "application/rss+xml; charset=utf-8",
+	site.FeedJson: "application/feed+json; charset=utf-8",
+}
+
+// AttachFeeds serves the feeds of recently changed pages at /feed.<format>,
+// with feeds for each section at /feeds/sections/<section>.<format>, and
+// for each taxonomy term at /feeds/<taxonomy>/<term>.<format>.
+func AttachFeeds(r gin.IRoutes) {
+	for format := range feedMediaTypes {
+		r.GET("/feed."+format, FeedHandler)
+	}
+	r.GET(FeedPath+"/*feed", FeedHandler)
+}
+
+func FeedHandler(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassFeed)
+
+	format := strings.TrimPrefix(path.Ext(c.Request.URL.Path), ".")
+	mediaType, ok := feedMediaTypes[format]
+	if !ok {
+		Page(c)
+		return
+	}
+
+	i := s.FeedPages(s.VisibleIndex(c))
+	title := s.conf.SiteConfig.Title
+	link := s.conf.SiteUrl("/")
+
+	if scope := strings.TrimSuffix(strings.Trim(c.Param("feed"), "/"), "."+format); scope != "" {
+		i, title, link, ok = s.scopeFeed(i, scope)
+		if !ok {
+			Page(c)
+			return
+		}
+	}
+
+	feed := s.BuildFeed(c, i, title, link)
+
+	c.Header("Content-Type", mediaType)
+	c.Status(http.StatusOK)
+
+	err := feed.Write(c.Writer, format)
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// FeedPages narrows an index down to the pages included in feeds.
+func (s *Site) FeedPages(i *site.PageIndex) *site.PageIndex {
+	return s.RenderedPages(i).Filter(func(p *site.PageEntry) bool {
+		return s.conf.SiteConfig.Feed.Includes(p.Section())
+	})
+}
+
+// scopeFeed narrows the pages of a feed down to a section, as in
+// "sections/ops", or to a taxonomy term, as in "tags/billing".
+func (s *Site) scopeFeed(i *site.PageIndex, scope string) (*site.PageIndex, string, string, bool) {
+	kind, name := path.Split(scope)
+	kind = strings.TrimSuffix(kind, "/")
+
+	if kind == "sections" {
+		scoped := i.Filter(func(p *site.PageEntry) bool {
+			return p.Section() == name
+		})
+		if len(scoped.Pages) == 0 {
+			return nil, "", "", false
+		}
+
+		crumb := i.SectionCrumb(s.conf, name)
+		if crumb.Url == "" {
+			crumb.Url = s.conf.SiteUrl("/")
+		}
+
+		return scoped, s.conf.SiteConfig.Title + ": " + crumb.Label, crumb.Url, true
+	}
+
+	for _, tc := range s.conf.SiteConfig.Taxonomies {
+		if tc.Path != kind {
+			continue
+		}
+
+		t, ok := i.Taxonomies[tc.Name]
+		if !ok {
+			break
+		}
+		term, ok := t.TermLookup[name]
+		if !ok {
+			break
+		}
+
+		scoped := i.Filter(func(p *site.PageEntry) bool {
+			for _, tp := range term.Pages {
+				if tp == p {
+					return true
+				}
+			}
+			return false
+		})
+
+		return scoped, s.conf.SiteConfig.Title + ": " + term.Name, term.Url, true
+	}
+
+	return nil, "", "", false
+}
+
+// BuildFeed lists the most recently updated pages of an index, with the
+// summary of each page and, if the site is set up for it, the whole page.
+func (s *Site) BuildFeed(c *gin.Context, i *site.PageIndex, title string, link string) *site.Feed {
+	conf := s.conf.SiteConfig.Feed
+
+	feed := &site.Feed{
+		Title:   title,
+		Link:    s.absoluteUrl(c, link),
+		FeedUrl: s.absoluteUrl(c, c.Request.URL.Path),
+		Updated: i.Built,
+	}
+
+	// Links and embeds resolve against every page the user can read, not
+	// just the pages in the feed
+	visible := s.VisibleIndex(c)
+	for _, p := range i.RecentPages(conf.Size) {
+		content := s.feedContent(c, visible, p)
+
+		entry := site.FeedEntry{
+			Title:   p.Label,
+			Url:     s.absoluteUrl(c, p.Url),
+			Updated: p.Updated(),
+			Summary: p.Summary,
+			Tags:    p.Tags,
+		}
+		if entry.Summary == "" {
+			entry.Summary = site.Summarize(content, feedSummaryLength)
+		}
+		if conf.Content {
+			entry.Content = string(content)
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	}
+
+	return feed
+}
+
+// feedContent renders a page in the same way as Page, without the page
+// template. Links are made absolute, so they still work in feed readers.
+func (s *Site) feedContent(c *gin.Context, visible *site.PageIndex, p *site.PageEntry) []byte {
+	renderer, ok := s.renderers[p.Extension]
+	if !ok || renderer.MediaType() != gin.MIMEHTML {
+		return nil
+	}
+
+	buf := bytes.Buffer{}
+	data := resource.InitRenderData(c, filepath.Join(s.conf.SitePath, p.Path))
+	s.pages.Prepare(data, visible, nil)
+	err := renderer.Render(&buf, data)
+	if err != nil {
+		log.Warnf("Failed to render [%s] for a feed: %s", p.Path, err)
+		return nil
+	}
+
+	return site.RewriteLinks(buf.Bytes(), func(href string) string {
+		if strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//") {
+			return s.absoluteUrl(c, href)
+		}
+		return href
+	})
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type FeedTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	e      *httpexpect.Expect
+}
+
+func TestFeedTestSuite(t *testing.T) {
+	suite.Run(t, new(FeedTestSuite))
+}
+
+func (t *FeedTestSuite) SetupTest() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "feed01"))
+
+	t.server = httptest.NewServer(st.Handler())
+	t.e = httpexpect.New(t.T(), t.server.URL)
+}
+
+func (t *FeedTestSuite) TearDownTest() {
+	t.server.Close()
+}
+
+// jsonFeed reads a JSON feed, which is not served as application/json.
+func (t *FeedTestSuite) jsonFeed(path string) *httpexpect.Object {
+	r := t.e.GET(path).Expect().
+		Status(http.StatusOK)
+	r.Header("Content-Type").Equal("application/feed+json; charset=utf-8")
+
+	var feed interface{}
+	t.Require().NoError(json.Unmarshal([]byte(r.Body().Raw()), &feed))
+
+	return t.e.Value(feed).Object()
+}
+
+func (t *FeedTestSuite) TestAtom() {
+	r := t.e.GET("/feed.atom").Expect().
+		Status(http.StatusOK)
+
+	r.Header("Content-Type").Equal("application/atom+xml; charset=utf-8")
+	r.Body().
+		Contains(`<id>https://docs.example.com/feed.atom</id>`).
+		Contains(`<updated>2020-03-01T09:30:00Z</updated>`).
+		Contains(`<link href="https://docs.example.com/feed.atom" rel="self"></link>`).
+		Contains(`<summary>How to restart the service.</summary>`).
+		Contains(`<summary>Switch the database over to the replica, and wait for the primary to catch up.</summary>`).
+		Contains(`&lt;a href=&#34;https://docs.example.com/ops/failover&#34;&gt;failover&lt;/a&gt;`).
+		Contains(`<category term="oncall"></category>`).
+		Contains(`<id>https://docs.example.com/index</id>`).
+		// Beyond the size of the feed, or not in a feed section
+		NotContains(`/ops/deploy`).
+		NotContains(`/dev/setup`)
+}
+
+func (t *FeedTestSuite) TestRss() {
+	r := t.e.GET("/feed.rss").Expect().
+		Status(http.StatusOK)
+
+	r.Header("Content-Type").Equal("application/rss+xml; charset=utf-8")
+	r.Body().
+		Contains(`<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">`).
+		Contains(`<guid isPermaLink="true">https://docs.example.com/ops/restart</guid>`).
+		Contains(`<pubDate>Sun, 01 Mar 2020 09:30:00 +0000</pubDate>`).
+		Contains(`<content:encoded>&lt;h1 id=&#34;restart&#34;&gt;Restart&lt;/h1&gt;`)
+}
+
+func (t *FeedTestSuite) TestJson() {
+	feed := t.jsonFeed("/feed.json")
+	feed.ValueEqual("version", "https://jsonfeed.org/version/1.1")
+	feed.ValueEqual("feed_url", "https://docs.example.com/feed.json")
+
+	items := feed.Value("items").Array()
+	items.Length().Equal(3)
+	items.Element(0).Object().
+		ValueEqual("url", "https://docs.example.com/ops/restart").
+		ValueEqual("date_modified", "2020-03-01T09:30:00Z").
+		ValueEqual("tags", []string{"oncall"})
+	items.Element(1).Object().
+		Value("content_html").String().
+		Contains(`<img src="https://docs.example.com/ops/diagram.png" alt="diagram"/>`)
+}
+
+func (t *FeedTestSuite) TestSectionFeed() {
+	items := t.jsonFeed("/feeds/sections/ops.json").
+		ValueEqual("title", "Feed01: Ops").
+		Value("items").Array()
+
+	items.Length().Equal(3)
+	items.Element(2).Object().ValueEqual("url", "https://docs.example.com/ops/deploy")
+
+	// Not included in the feeds
+	t.e.GET("/feeds/sections/dev.atom").Expect().
+		Status(http.StatusNotFound)
+}
+
+func (t *FeedTestSuite) TestTermFeed() {
+	items := t.jsonFeed("/feeds/tags/oncall.json").
+		ValueEqual("title", "Feed01: oncall").
+		ValueEqual("home_page_url", "https://docs.example.com/tags/oncall").
+		Value("items").Array()
+
+	items.Length().Equal(2)
+	items.Element(0).Object().ValueEqual("url", "https://docs.example.com/ops/restart")
+	items.Element(1).Object().ValueEqual("url", "https://docs.example.com/ops/failover")
+
+	t.e.GET("/feeds/tags/missing.rss").Expect().
+		Status(http.StatusNotFound)
+	t.e.GET("/feeds/tags/oncall.txt").Expect().
+		Status(http.StatusNotFound)
+}
+
+func (t *FeedTestSuite) TestFeed_RestrictedEmbed() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "auth01"))
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/feed.atom").WithBasicAuth("alice", "password").Expect().
+		Status(http.StatusOK).Body().Contains("Restricted.")
+
+	body := e.GET("/feed.atom").WithBasicAuth("bob", "password").Expect().
+		Status(http.StatusOK).Body()
+	body.Contains("Digest")
+	body.NotContains("Restricted.")
+	body.NotContains("Incident Response")
+}
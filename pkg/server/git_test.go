@@ -0,0 +1,107 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type GitTestSuite struct {
+	suite.Suite
+	repo string
+}
+
+func TestGitTestSuite(t *testing.T) {
+	suite.Run(t, new(GitTestSuite))
+}
+
+func (t *GitTestSuite) SetupTest() {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.T().Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "mdsite-git")
+	t.Require().NoError(err)
+	t.repo = dir
+
+	t.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "guide.md"), []byte("# Guide\n"), 0644))
+	t.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "draft.md"), []byte("# Draft\n"), 0644))
+
+	t.git("init", "-q")
+	t.git("add", "guide.md")
+	t.git("commit", "-q", "-m", "Write the guide")
+}
+
+func (t *GitTestSuite) TearDownTest() {
+	os.RemoveAll(t.repo)
+}
+
+func (t *GitTestSuite) git(args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = t.repo
+	cmd.Env = append(os.Environ(),
+		"HOME="+t.repo,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Alice Admin",
+		"GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_AUTHOR_DATE=2020-03-02T09:00:00Z",
+		"GIT_COMMITTER_NAME=Alice Admin",
+		"GIT_COMMITTER_EMAIL=alice@example.com",
+		"GIT_COMMITTER_DATE=2020-03-02T09:00:00Z",
+	)
+
+	out, err := cmd.CombinedOutput()
+	t.Require().NoError(err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+func (t *GitTestSuite) TestLastUpdated() {
+	v := testSiteValues(&t.Suite, "git01")
+	v.SitePath = t.repo
+
+	st := loadTestSite(&t.Suite, v)
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	hash := t.git("rev-parse", "--short=7", "HEAD")
+
+	e.GET("/guide").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains("<footer>Last updated by Alice Admin on 2 March 2020 in " + hash + ": Write the guide</footer>")
+
+	// Not committed yet
+	e.GET("/draft").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains("<footer>Last updated on ").
+		NotContains("Alice Admin")
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Readiness states
+const (
+	HealthOk          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+type HealthResponse struct {
+	Status string       `json:"status"`
+	Uptime float64      `json:"uptimeSeconds"`
+	Sites  []SiteHealth `json:"sites,omitempty"`
+}
+
+type SiteHealth struct {
+	Name           string   `json:"name"`
+	Status         string   `json:"status"`
+	Pages          int      `json:"pages"`
+	Built          string   `json:"built,omitempty"`
+	IndexAge       float64  `json:"indexAgeSeconds"`
+	IndexError     string   `json:"indexError,omitempty"`
+	ConfigPath     string   `json:"configPath"`
+	TemplateErrors []string `json:"templateErrors,omitempty"`
+}
+
+func AttachHealth(r gin.IRoutes) {
+	r.GET("/healthz", Healthz)
+	r.GET("/readyz", Readyz)
+}
+
+// Healthz reports that the process is up and able to handle requests.
+func Healthz(c *gin.Context) {
+	r := HealthResponse{Status: HealthOk}
+
+	if d := ContextDispatcher(c); d != nil {
+		r.Uptime = time.Since(d.started).Seconds()
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// Readyz reports whether every site has been indexed and has its templates
+// loaded. A site that failed to reindex keeps serving its previous index, so
+// it is degraded rather than unavailable.
+func Readyz(c *gin.Context) {
+	d := ContextDispatcher(c)
+	if d == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	r := HealthResponse{
+		Status: HealthOk,
+		Uptime: time.Since(d.started).Seconds(),
+	}
+	sites := d.Sites()
+	if len(sites) == 0 {
+		r.Status = HealthUnavailable
+	}
+
+	for _, hs := range sites {
+		sh := hs.Site.Health()
+		sh.Name = hs.Name
+
+		switch {
+		case sh.Status == HealthUnavailable:
+			r.Status = HealthUnavailable
+		case sh.Status == HealthDegraded && r.Status == HealthOk:
+			r.Status = HealthDegraded
+		}
+
+		r.Sites = append(r.Sites, sh)
+	}
+
+	code := http.StatusOK
+	if r.Status == HealthUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, r)
+}
+
+// Health reports on the index and templates of the site, without triggering
+// an index build.
+func (s *Site) Health() SiteHealth {
+	is := s.IndexStatus()
+
+	h := SiteHealth{
+		Status:         HealthOk,
+		Pages:          is.Pages,
+		IndexError:     is.Error,
+		ConfigPath:     s.conf.ConfigPath,
+		TemplateErrors: s.templateErrors(),
+	}
+
+	if is.Indexed {
+		h.Built = is.Built.Format(time.RFC3339)
+		h.IndexAge = time.Since(is.Built).Seconds()
+	}
+
+	switch {
+	case !is.Indexed || len(h.TemplateErrors) > 0:
+		h.Status = HealthUnavailable
+	case is.Error != "":
+		h.Status = HealthDegraded
+	}
+
+	return h
+}
+
+func (s *Site) templateErrors() []string {
+	var errs []string
+
+	sc := &s.conf.SiteConfig
+	if sc.Global.PageTemplate == nil {
+		errs = append(errs, "global.pageTemplate: not configured")
+	}
+	if sc.Global.TocTemplate == nil {
+		errs = append(errs, "global.tocTemplate: not configured")
+	}
+
+	for name, t := range sc.Templates() {
+		if !t.Resolved() {
+			errs = append(errs, fmt.Sprintf("%s: template has not been resolved", name))
+		}
+	}
+	sort.Strings(errs)
+
+	return errs
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type HealthTestSuite struct {
+	suite.Suite
+}
+
+func TestHealthTestSuite(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+func (t *HealthTestSuite) serve(d *Dispatcher) (*httpexpect.Expect, func()) {
+	server := httptest.NewServer(d.engine)
+
+	return httpexpect.New(t.T(), server.URL), server.Close
+}
+
+func (t *HealthTestSuite) TestHealthz() {
+	conf := config.Create()
+	conf.EnableTestMode()
+
+	e, done := t.serve(CreateDispatcher(conf))
+	defer done()
+
+	e.GET("/healthz").Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("status", HealthOk)
+}
+
+func (t *HealthTestSuite) TestReadyz_NoSites() {
+	conf := config.Create()
+	conf.EnableTestMode()
+
+	e, done := t.serve(CreateDispatcher(conf))
+	defer done()
+
+	e.GET("/readyz").Expect().
+		Status(http.StatusServiceUnavailable).
+		JSON().Object().ValueEqual("status", HealthUnavailable)
+}
+
+func (t *HealthTestSuite) TestReadyz_Lifecycle() {
+	conf := testSiteValues(&t.Suite, "test01")
+
+	d := CreateDispatcher(conf)
+	st := loadTestSite(&t.Suite, conf)
+	d.AttachSite(st)
+
+	e, done := t.serve(d)
+	defer done()
+
+	// Not indexed yet
+	e.GET("/readyz").Expect().
+		Status(http.StatusServiceUnavailable).
+		JSON().Path("$.sites[0].status").Equal(HealthUnavailable)
+
+	st.Index()
+	ready := e.GET("/readyz").Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	ready.ValueEqual("status", HealthOk)
+	site := ready.Value("sites").Array().Element(0).Object()
+	site.ValueEqual("name", "default")
+	site.ValueEqual("pages", 4)
+	site.ValueEqual("configPath", conf.ConfigPath)
+	site.NotContainsKey("indexError")
+	site.NotContainsKey("templateErrors")
+
+	// A failed reindex keeps serving the previous index
+	st.conf.SitePath = "/@@@@/BadPATH"
+	_, err := st.ReIndex()
+	t.Error(err)
+
+	degraded := e.GET("/readyz").Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	degraded.ValueEqual("status", HealthDegraded)
+	site = degraded.Value("sites").Array().Element(0).Object()
+	site.ValueEqual("pages", 4)
+	site.Value("indexError").String().NotEmpty()
+}
+
+func (t *HealthTestSuite) TestReadyz_TemplateErrors() {
+	conf := testSiteValues(&t.Suite, "test01")
+
+	d := CreateDispatcher(conf)
+	st, err := NewSite(conf, config.Site{})
+	t.Require().NoError(err)
+	st.Index()
+	d.AttachSite(st)
+
+	e, done := t.serve(d)
+	defer done()
+
+	e.GET("/readyz").Expect().
+		Status(http.StatusServiceUnavailable).
+		JSON().Path("$.sites[0].templateErrors").Array().
+		Contains("global.pageTemplate: not configured")
+}
+
+func (t *HealthTestSuite) TestReadyz_SkipsAuth() {
+	conf := testSiteValues(&t.Suite, "auth01")
+
+	d := CreateDispatcher(conf)
+	st := loadTestSite(&t.Suite, conf)
+	st.Index()
+	d.AttachSite(st)
+
+	e, done := t.serve(d)
+	defer done()
+
+	e.GET("/page").Expect().Status(http.StatusUnauthorized)
+	e.GET("/readyz").Expect().Status(http.StatusOK)
+	e.GET("/healthz").Expect().Status(http.StatusOK)
+}
@@ -22,8 +22,8 @@ import (
 	"strings"
 )
 
-func AttachIndex(d *Dispatcher) {
-	d.engine.GET("/", Index)
+func AttachIndex(r gin.IRoutes) {
+	r.GET("/", Index)
 }
 
 func Index(c *gin.Context) {
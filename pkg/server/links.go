@@ -0,0 +1,73 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"path/filepath"
+)
+
+const LinkReportPath = site.LinkReportPath
+
+type LinkReport struct {
+	Pages       int         `json:"pages"`
+	BrokenLinks int         `json:"brokenLinks"`
+	BrokenPages []PageLinks `json:"brokenPages"`
+}
+
+type PageLinks struct {
+	Url   string      `json:"url"`
+	Path  string      `json:"path"`
+	Links []site.Link `json:"links"`
+}
+
+func AttachLinkReport(r gin.IRoutes) {
+	r.GET(LinkReportPath, LinkReportHandler)
+}
+
+// LinkReportHandler lists the broken links on every page the user can read.
+func LinkReportHandler(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassOther)
+
+	c.JSON(http.StatusOK, BuildLinkReport(s.VisibleIndex(c)))
+}
+
+func BuildLinkReport(i *site.PageIndex) LinkReport {
+	r := LinkReport{
+		Pages:       len(i.Pages),
+		BrokenPages: []PageLinks{},
+	}
+
+	for _, p := range i.Pages {
+		broken := p.BrokenLinks()
+		if len(broken) == 0 {
+			continue
+		}
+
+		r.BrokenLinks += len(broken)
+		r.BrokenPages = append(r.BrokenPages, PageLinks{
+			Url:   p.Url,
+			Path:  filepath.ToSlash(p.Path),
+			Links: broken,
+		})
+	}
+
+	return r
+}
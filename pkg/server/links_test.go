@@ -0,0 +1,166 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type LinksTestSuite struct {
+	suite.Suite
+}
+
+func TestLinksTestSuite(t *testing.T) {
+	suite.Run(t, new(LinksTestSuite))
+}
+
+func (t *LinksTestSuite) TestScanLinks() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "links01"))
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	i := st.Index()
+
+	guide := i.PageLookup["/guide"]
+	t.Require().NotNil(guide)
+
+	broken := []string{}
+	for _, l := range guide.BrokenLinks() {
+		broken = append(broken, l.Href)
+	}
+	t.Equal([]string{"/renamed-page", "/images/missing.png"}, broken)
+
+	deep := i.PageLookup["/docs/deep"]
+	t.Require().NotNil(deep)
+	t.Require().Len(deep.BrokenLinks(), 1)
+	t.Equal("/docs/missing-sibling", deep.BrokenLinks()[0].Target)
+
+	t.Empty(i.PageLookup["/other"].BrokenLinks())
+}
+
+func (t *LinksTestSuite) TestLinkReport() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "links01"))
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	report := e.GET(LinkReportPath).Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	report.ValueEqual("brokenLinks", 3)
+	report.Path("$.brokenPages[*].url").Array().ContainsOnly("/guide", "/docs/deep")
+}
+
+func (t *LinksTestSuite) TestDevModeMarking() {
+	v := testSiteValues(&t.Suite, "links01")
+	v.DevMode = true
+	st := loadTestSite(&t.Suite, v)
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	body := e.GET("/guide").Expect().
+		Status(http.StatusOK).
+		Body()
+
+	body.Contains(`href="/renamed-page" class="` + site.BrokenLinkClass + `"`)
+	body.NotContains(`href="/other" class=`)
+}
+
+func (t *LinksTestSuite) TestNoMarkingByDefault() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "links01"))
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/guide").Expect().
+		Status(http.StatusOK).
+		Body().NotContains(site.BrokenLinkClass)
+}
+
+func (t *LinksTestSuite) TestSourceLinks() {
+	v := testSiteValues(&t.Suite, "links01")
+	v.BasePath = "/docs-site"
+	st := loadTestSite(&t.Suite, v)
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	guide := e.GET("/docs-site/guide").Expect().
+		Status(http.StatusOK).
+		Body()
+
+	guide.Contains(`href="/docs-site/docs/deep#intro"`)
+	guide.Contains(`href="/docs-site/other"`)
+	guide.Contains(`src="/docs-site/images/logo.png"`)
+	guide.Contains(`href="https://example.com/page"`)
+	guide.Contains(`href="#top"`)
+	guide.Contains(`href="/toc"`)
+
+	e.GET("/docs-site/docs/deep").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`href="/docs-site/guide?tab=1"`).
+		Contains(`href="/docs-site/other#intro"`)
+}
+
+func (t *LinksTestSuite) TestBacklinks() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "links01"))
+	_, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	backlinks := e.GET(ApiPath + "/pages/other/backlinks").Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	backlinks.Path("$.page.url").Equal("/other")
+	backlinks.Path("$.backlinks[*].url").Array().Equal([]string{"/docs/deep", "/guide"})
+	backlinks.Path("$.backlinks[0].path").Equal("docs/deep.md")
+
+	e.GET(ApiPath + "/pages/docs/deep/backlinks").Expect().
+		Status(http.StatusOK).
+		JSON().Path("$.backlinks[*].url").Array().Equal([]string{"/guide"})
+
+	e.GET(ApiPath + "/pages/missing/backlinks").Expect().
+		Status(http.StatusNotFound)
+	e.GET(ApiPath + "/pages/other/unknown").Expect().
+		Status(http.StatusNotFound)
+
+	e.GET("/other").Expect().
+		Status(http.StatusOK).
+		Body().Contains(`<ul class="backlinks"><li><a href="/docs/deep">Deep</a></li><li><a href="/guide">Guide</a></li></ul>`)
+}
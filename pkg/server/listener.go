@@ -0,0 +1,186 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/zpxio/mdsite/pkg/config"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The first file descriptor passed by a socket activating service manager.
+const listenFdsStart = 3
+
+type InheritedListener struct {
+	Name     string
+	Listener net.Listener
+}
+
+// InheritListeners takes over the listeners passed to this process using the
+// systemd socket activation protocol (LISTEN_PID, LISTEN_FDS, LISTEN_FDNAMES).
+func InheritListeners() ([]InheritedListener, error) {
+	return inheritListenersFrom(listenFdsStart)
+}
+
+func inheritListenersFrom(startFd int) ([]InheritedListener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if fdNames := os.Getenv("LISTEN_FDNAMES"); fdNames != "" {
+		names = strings.Split(fdNames, ":")
+	}
+
+	// The descriptors are ours now, so don't pass them on to children
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]InheritedListener, 0, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("fd%d", startFd+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(startFd+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherited descriptor [%s] is not a listener: %s", name, err)
+		}
+
+		listeners = append(listeners, InheritedListener{Name: name, Listener: l})
+	}
+
+	return listeners, nil
+}
+
+// assignInherited picks the plain and TLS listeners out of the inherited set.
+// Listeners named "https" or "tls" are used for TLS, everything else is used
+// in the order it was passed.
+func assignInherited(inherited []InheritedListener, wantTls bool) (net.Listener, net.Listener) {
+	var plain, secure net.Listener
+	var unnamed []net.Listener
+
+	for _, il := range inherited {
+		switch strings.ToLower(il.Name) {
+		case "https", "tls":
+			if secure == nil {
+				secure = il.Listener
+				continue
+			}
+		case "http":
+			if plain == nil {
+				plain = il.Listener
+				continue
+			}
+		}
+		unnamed = append(unnamed, il.Listener)
+	}
+
+	for _, l := range unnamed {
+		if plain == nil {
+			plain = l
+		} else if wantTls && secure == nil {
+			secure = l
+		} else {
+			log.Warnf("Ignoring unused inherited listener: %s", describeAddr(l.Addr()))
+			l.Close()
+		}
+	}
+
+	return plain, secure
+}
+
+func listenUnix(socket string, mode os.FileMode) (net.Listener, error) {
+	if fs, err := os.Lstat(socket); err == nil {
+		if fs.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socket)
+		}
+
+		// A socket that still accepts connections belongs to a running server
+		conn, err := net.DialTimeout("unix", socket, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", socket)
+		}
+
+		// Clear out a stale socket left behind by an unclean shutdown
+		err = os.Remove(socket)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(socket, mode)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// listenAddress listens on an ip:port address, or a unix:<path> socket.
+func listenAddress(addr string, mode os.FileMode) (net.Listener, error) {
+	if strings.HasPrefix(addr, config.UnixSocketPrefix) {
+		return listenUnix(strings.TrimPrefix(addr, config.UnixSocketPrefix), mode)
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+func describeAddr(addr net.Addr) string {
+	if addr.Network() == "unix" {
+		return config.UnixSocketPrefix + addr.String()
+	}
+
+	return addr.String()
+}
+
+func addrUrl(scheme string, addr net.Addr) url.URL {
+	if addr.Network() == "unix" {
+		return url.URL{
+			Scheme: "unix",
+			Path:   addr.String(),
+		}
+	}
+
+	return url.URL{
+		Scheme: scheme,
+		Host:   addr.String(),
+	}
+}
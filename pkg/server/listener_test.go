@@ -0,0 +1,176 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type ListenerTestSuite struct {
+	suite.Suite
+	tempDir string
+}
+
+func TestListenerTestSuite(t *testing.T) {
+	suite.Run(t, new(ListenerTestSuite))
+}
+
+func (t *ListenerTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "mdsite-sock")
+	t.Require().NoError(err)
+	t.tempDir = dir
+}
+
+func (t *ListenerTestSuite) TearDownTest() {
+	os.RemoveAll(t.tempDir)
+}
+
+func unixClient(socket string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
+
+func (t *ListenerTestSuite) TestUnixSocket() {
+	socket := filepath.Join(t.tempDir, "mdsite.sock")
+
+	conf := config.Create()
+	conf.EnableTestMode()
+	conf.ListenSocket = socket
+	conf.SocketMode = 0600
+
+	d := CreateDispatcher(conf)
+	d.Start()
+	defer d.Shutdown()
+
+	u := d.ServerUrl()
+	t.Equal("unix", u.Scheme)
+	t.Equal(socket, u.Path)
+
+	fs, err := os.Stat(socket)
+	t.Require().NoError(err)
+	t.Equal(os.FileMode(0600), fs.Mode().Perm())
+
+	resp, err := unixClient(socket).Get("http://mdsite/ping")
+	t.Require().NoError(err)
+	resp.Body.Close()
+	t.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (t *ListenerTestSuite) TestUnixSocket_Stale() {
+	socket := filepath.Join(t.tempDir, "stale.sock")
+
+	// Leave a socket file behind without cleaning it up
+	stale, err := net.Listen("unix", socket)
+	t.Require().NoError(err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := listenUnix(socket, config.DefaultSocketMode)
+	t.Require().NoError(err)
+	l.Close()
+}
+
+func (t *ListenerTestSuite) TestUnixSocket_InUse() {
+	socket := filepath.Join(t.tempDir, "live.sock")
+
+	live, err := listenUnix(socket, config.DefaultSocketMode)
+	t.Require().NoError(err)
+	defer live.Close()
+	go func() {
+		for {
+			conn, err := live.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, err = listenUnix(socket, config.DefaultSocketMode)
+	t.Require().Error(err)
+	t.Contains(err.Error(), "in use")
+
+	// The running listener keeps its socket
+	conn, err := net.Dial("unix", socket)
+	t.Require().NoError(err)
+	conn.Close()
+}
+
+func (t *ListenerTestSuite) TestUnixSocket_NotSocket() {
+	file := filepath.Join(t.tempDir, "data.txt")
+	t.Require().NoError(ioutil.WriteFile(file, []byte("keep me"), 0644))
+
+	_, err := listenUnix(file, config.DefaultSocketMode)
+	t.Require().Error(err)
+	t.Contains(err.Error(), "not a socket")
+
+	data, err := ioutil.ReadFile(file)
+	t.Require().NoError(err)
+	t.Equal("keep me", string(data))
+}
+
+func (t *ListenerTestSuite) TestInheritListeners() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Require().NoError(err)
+	defer l.Close()
+
+	f, err := l.(*net.TCPListener).File()
+	t.Require().NoError(err)
+	defer f.Close()
+
+	os.Setenv("LISTEN_PID", fmt.Sprintf("%d", os.Getpid()))
+	os.Setenv("LISTEN_FDS", "1")
+	os.Setenv("LISTEN_FDNAMES", "https")
+
+	inherited, err := inheritListenersFrom(int(f.Fd()))
+	t.Require().NoError(err)
+	t.Require().Len(inherited, 1)
+	t.Equal("https", inherited[0].Name)
+	t.Equal(l.Addr().String(), inherited[0].Listener.Addr().String())
+
+	t.Empty(os.Getenv("LISTEN_FDS"))
+
+	plain, secure := assignInherited(inherited, true)
+	t.Nil(plain)
+	t.Equal(inherited[0].Listener, secure)
+	secure.Close()
+}
+
+func (t *ListenerTestSuite) TestInheritListeners_OtherProcess() {
+	os.Setenv("LISTEN_PID", "1")
+	os.Setenv("LISTEN_FDS", "1")
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+
+	inherited, err := InheritListeners()
+	t.NoError(err)
+	t.Empty(inherited)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/metrics"
+	"net/http"
+	"strconv"
+)
+
+type serverMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.CounterVec
+	latency  *metrics.HistogramVec
+	render   *metrics.HistogramVec
+}
+
+func (d *Dispatcher) createMetrics() *serverMetrics {
+	r := metrics.NewRegistry()
+
+	m := serverMetrics{
+		registry: r,
+		requests: r.NewCounterVec("mdsite_http_requests_total",
+			"Requests handled, by site, route class, status and resource mode.",
+			"site", "class", "status", "mode"),
+		latency: r.NewHistogramVec("mdsite_http_request_duration_seconds",
+			"Time taken to handle requests, by site, route class, status and resource mode.",
+			metrics.DefaultBuckets, "site", "class", "status", "mode"),
+		render: r.NewHistogramVec("mdsite_render_duration_seconds",
+			"Time taken to render page content, by site and renderer.",
+			metrics.DefaultBuckets, "site", "renderer"),
+	}
+
+	r.NewGaugeFunc("mdsite_index_pages", "Pages in the current site index.",
+		[]string{"site"}, d.indexGauge(func(hs *HostedSite) float64 {
+			return float64(hs.Site.IndexStatus().Pages)
+		}))
+	r.NewGaugeFunc("mdsite_index_build_duration_seconds", "Time taken by the last index build.",
+		[]string{"site"}, d.indexGauge(func(hs *HostedSite) float64 {
+			return hs.Site.IndexStatus().BuildDuration.Seconds()
+		}))
+	r.NewGaugeFunc("mdsite_index_build_timestamp_seconds", "Unix time of the last index build.",
+		[]string{"site"}, d.indexGauge(func(hs *HostedSite) float64 {
+			return float64(hs.Site.IndexStatus().Built.UnixNano()) / 1e9
+		}))
+
+	return &m
+}
+
+// indexGauge reads a value from every indexed site.
+func (d *Dispatcher) indexGauge(value func(hs *HostedSite) float64) func() []metrics.Sample {
+	return func() []metrics.Sample {
+		var samples []metrics.Sample
+
+		for _, hs := range d.Sites() {
+			if !hs.Site.IndexStatus().Indexed {
+				continue
+			}
+
+			samples = append(samples, metrics.Sample{
+				Values: []string{hs.Name},
+				Value:  value(hs),
+			})
+		}
+
+		return samples
+	}
+}
+
+func (m *serverMetrics) observe(c *gin.Context, rec *RequestRecord) {
+	status := strconv.Itoa(c.Writer.Status())
+
+	m.requests.Inc(rec.Site, rec.Class, status, rec.Mode)
+	m.latency.Observe(rec.Latency.Seconds(), rec.Site, rec.Class, status, rec.Mode)
+
+	if rec.Mode != "" {
+		m.render.Observe(rec.Render.Seconds(), rec.Site, rec.Mode)
+	}
+}
+
+func AttachMetrics(r gin.IRoutes) {
+	r.GET("/metrics", Metrics)
+}
+
+func Metrics(c *gin.Context) {
+	d := ContextDispatcher(c)
+	if d == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+
+	err := d.metrics.registry.WriteText(c.Writer)
+	if err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (t *MetricsTestSuite) TestMetrics() {
+	conf := testSiteValues(&t.Suite, "test01")
+
+	d := CreateDispatcher(conf)
+	st := loadTestSite(&t.Suite, conf)
+	st.Index()
+	d.AttachSite(st)
+
+	server := httptest.NewServer(d.engine)
+	defer server.Close()
+
+	e := httpexpect.New(t.T(), server.URL)
+	e.GET("/sample-01").Expect().Status(http.StatusOK)
+	e.GET("/sample-01").Expect().Status(http.StatusOK)
+	e.GET("/moved-away").Expect().Status(http.StatusNotFound)
+	e.GET("/style.css").Expect().Status(http.StatusNotFound)
+	e.GET("/toc").Expect().Status(http.StatusOK)
+	e.GET("/ping").Expect().Status(http.StatusOK)
+
+	t.Equal(float64(2), d.metrics.requests.Value("default", ClassPage, "200", "Markdown"))
+	t.Equal(float64(1), d.metrics.requests.Value("default", ClassPage, "404", "Not-Found"))
+	t.Equal(float64(1), d.metrics.requests.Value("default", ClassAsset, "404", "Not-Found"))
+	t.Equal(float64(1), d.metrics.requests.Value("default", ClassToc, "200", ""))
+	t.Equal(float64(1), d.metrics.requests.Value("", ClassPing, "200", ""))
+	t.Equal(uint64(2), d.metrics.render.Count("default", "Markdown"))
+
+	body := e.GET("/metrics").Expect().
+		Status(http.StatusOK).
+		ContentType("text/plain").
+		Body()
+	body.Contains(`mdsite_http_requests_total{site="default",class="page",status="200",mode="Markdown"} 2`)
+	body.Contains(`mdsite_http_request_duration_seconds_count{site="default",class="toc",status="200",mode=""} 1`)
+	body.Contains(`mdsite_render_duration_seconds_count{site="default",renderer="Markdown"} 2`)
+	body.Contains(`mdsite_index_pages{site="default"} 4`)
+	body.Contains(`mdsite_index_build_timestamp_seconds{site="default"}`)
+}
+
+func (t *MetricsTestSuite) TestMetrics_SkipsAuth() {
+	conf := testSiteValues(&t.Suite, "auth01")
+
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+
+	server := httptest.NewServer(d.engine)
+	defer server.Close()
+
+	e := httpexpect.New(t.T(), server.URL)
+	e.GET("/page").Expect().Status(http.StatusUnauthorized)
+	e.GET("/metrics").Expect().
+		Status(http.StatusOK).
+		Body().Contains("mdsite_http_requests_total")
+	e.GET("/ping").Expect().Status(http.StatusOK)
+}
+
+func (t *MetricsTestSuite) TestMetrics_AdminListener() {
+	conf := testSiteValues(&t.Suite, "test01")
+	conf.ListenIp = []byte{127, 0, 0, 1}
+	conf.AdminListen = "127.0.0.1:0"
+
+	d := CreateDispatcher(conf)
+	d.AttachSite(loadTestSite(&t.Suite, conf))
+	d.Start()
+	defer d.Shutdown()
+
+	admin := d.AdminUrl()
+	site := d.HttpUrl()
+	t.NotEqual(admin.Host, site.Host)
+
+	httpexpect.New(t.T(), admin.String()).GET("/metrics").Expect().
+		Status(http.StatusOK).
+		Body().Contains("mdsite_http_requests_total")
+	httpexpect.New(t.T(), site.String()).GET("/metrics").Expect().
+		Status(http.StatusNotFound)
+}
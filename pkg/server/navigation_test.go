@@ -0,0 +1,60 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type NavigationTestSuite struct {
+	suite.Suite
+}
+
+func TestNavigationTestSuite(t *testing.T) {
+	suite.Run(t, new(NavigationTestSuite))
+}
+
+func (t *NavigationTestSuite) TestPageNavigation() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "nav01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/ops/db/failover").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<nav class="crumbs"><a href="/index">Nav01</a> / <a href="/ops">Ops</a> / <span>Db</span> / Failover</nav>`).
+		Contains(`<nav class="pager"><a rel="prev" href="/ops/restart">Restart</a><a rel="next" href="/guides/index">Index</a></nav>`)
+
+	// Files that are not pages are skipped
+	e.GET("/guides/index").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<nav class="crumbs"><a href="/index">Nav01</a> / Index</nav>`).
+		Contains(`<nav class="pager"><a rel="prev" href="/ops/db/failover">Failover</a></nav>`)
+
+	e.GET("/missing").Expect().
+		Status(http.StatusNotFound).
+		Body().
+		Contains(`<nav class="crumbs"></nav>`).
+		Contains(`<nav class="pager"></nav>`)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type OutlineTestSuite struct {
+	suite.Suite
+}
+
+func TestOutlineTestSuite(t *testing.T) {
+	suite.Run(t, new(OutlineTestSuite))
+}
+
+func (t *OutlineTestSuite) TestMarkdownOutline() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "outline01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	outline := `<ul><li><a href="#install">Install</a><ul><li><a href="#packages">Packages</a></li></ul></li>` +
+		`<li><a href="#configure">Configure</a><ul><li><a href="#packages-1">Packages</a></li></ul></li>` +
+		`<li><a href="#install-1">Install</a></li><li><a href="#custom">Custom Heading</a></li></ul>`
+
+	e.GET("/setup").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<aside class="outline">` + outline + `</aside>`).
+		Contains(`<nav class="mdsite-toc">` + outline + `</nav>`).
+		Contains(`<h1 id="setup">Setup</h1>`).
+		Contains(`<h4 id="deep-detail">Deep Detail</h4>`).
+		Contains(`<h3 id="packages-1">Packages</h3>`).
+		Contains(`<h2 id="install-1">Install</h2>`).
+		Contains(`<h2 id="custom">Custom Heading</h2>`).
+		Contains("<pre><code>[TOC]\n</code></pre>")
+}
+
+func (t *OutlineTestSuite) TestHtmlOutline() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "outline01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/plain").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<aside class="outline"><ul><li><a href="#first">First</a></li><li><a href="#first-1">First</a></li></ul></aside>`).
+		Contains(`<h2 id="first">First</h2>`).
+		Contains(`<h2 id="first-1">First</h2>`)
+}
@@ -20,59 +20,123 @@ import (
 	"bytes"
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
-	"github.com/zpxio/mdsite/pkg/config"
 	"github.com/zpxio/mdsite/pkg/resource"
+	"github.com/zpxio/mdsite/pkg/site"
 	"html/template"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"time"
 )
 
-var resourceRenderer = make(map[string]resource.Renderer)
-var missingRenderer = resource.MissingResource{}
-
-func init() {
-	registerRenderer("md", resource.MarkdownResource{})
-	registerRenderer("txt", resource.TextResource{})
-	registerRenderer("html", resource.HtmlResource{})
-}
-
-func registerRenderer(suffix string, renderer resource.Renderer) {
-	resourceRenderer[suffix] = renderer
-}
-
-func AttachPageHandler(d *Dispatcher) {
-	d.engine.NoRoute(Page)
+func AttachPageHandler(e *gin.Engine) {
+	e.NoRoute(Page)
 }
 
 func Page(c *gin.Context) {
-	rc := c.Request.URL.Path
-	renderer, rcFile := FindResourceFile(c, rc)
+	s := ContextSite(c)
+	renderer, rcFile := s.FindResourceFile(c, SitePath(c, c.Request.URL.Path))
 
 	data := resource.InitRenderData(c, rcFile)
+	if renderer != s.missing {
+		// Only pages the user can read are linked to or embedded
+		s.pages.Prepare(data, s.VisibleIndex(c), nil)
+	}
 
 	// Set up headers
 	c.Header("X-Resource-Mode", renderer.ResourceMode())
 	c.Header("Content-Type", renderer.MediaType())
 
 	contentBuf := &bytes.Buffer{}
+	start := time.Now()
 	renderer.Render(contentBuf, data)
+	s.notePage(c, renderer, rcFile, time.Since(start))
+
+	if s.conf.DevMode {
+		s.markBrokenLinks(c, contentBuf)
+	}
 
 	pd := resource.InitRenderData(c, rcFile)
-	pd.Title = config.Global().SiteConfig.Title
+	pd.Title = s.Config().SiteConfig.Title
+	pd.BaseUrl = s.Config().BaseUrl()
 	pd.Content = template.HTML(contentBuf.String())
+	pd.Outline = data.Outline
+	s.addPageContext(c, pd)
+
+	s.Config().SiteConfig.Global.PageTemplate.Execute(c.Writer, pd)
+}
+
+// SitePath strips the base path prefix from a request path. Paths outside of
+// the prefix are returned as an empty string.
+func SitePath(c *gin.Context, requestPath string) string {
+	return site.StripBasePath(ContextConfig(c).BaseUrl(), requestPath)
+}
+
+// addPageContext adds the requested page to the template data, along with
+// where it sits in the site. Only pages the user can read are included.
+func (s *Site) addPageContext(c *gin.Context, pd *resource.RenderData) {
+	i := s.VisibleIndex(c)
+
+	p, ok := i.PageLookup[s.conf.SiteUrl(SitePath(c, c.Request.URL.Path))]
+	if !ok {
+		return
+	}
+
+	pd.Page = p
+	pd.Breadcrumbs = i.Breadcrumbs(s.conf, p)
+	pd.Prev, pd.Next = s.RenderedPages(i).Neighbours(p)
+	pd.Backlinks = i.BacklinksTo(p)
+}
+
+// RenderedPages narrows an index down to the pages the site renders, leaving
+// out other files such as images.
+func (s *Site) RenderedPages(i *site.PageIndex) *site.PageIndex {
+	return i.Filter(s.pages.Rendered)
+}
+
+// markBrokenLinks highlights the broken links found on the page when it
+// was indexed.
+func (s *Site) markBrokenLinks(c *gin.Context, content *bytes.Buffer) {
+	p, ok := s.Index().PageLookup[s.conf.SiteUrl(SitePath(c, c.Request.URL.Path))]
+	if !ok || len(p.BrokenLinks()) == 0 {
+		return
+	}
 
-	config.Global().SiteConfig.Global.PageTemplate.Execute(c.Writer, pd)
+	marked := site.MarkBrokenLinks(content.Bytes(), p.BrokenLinks())
+	content.Reset()
+	content.Write(marked)
 }
 
-func FindResourceFile(c *gin.Context, resource string) (resource.Renderer, string) {
-	base := SiteBaseDirectory(c)
+// notePage records the resolved page for request tracking. Requests for
+// paths with a file extension are never pages, so they are counted as assets.
+func (s *Site) notePage(c *gin.Context, renderer resource.Renderer, rcFile string, render time.Duration) {
+	if renderer == s.missing {
+		class := ClassPage
+		if path.Ext(c.Request.URL.Path) != "" {
+			class = ClassAsset
+		}
+		notePage(c, class, "", renderer.ResourceMode(), render)
+		return
+	}
+
+	page, _ := filepath.Rel(s.conf.SitePath, rcFile)
+	notePage(c, ClassPage, filepath.ToSlash(page), renderer.ResourceMode(), render)
+}
+
+func (s *Site) FindResourceFile(c *gin.Context, resource string) (resource.Renderer, string) {
+	if resource == "" {
+		c.Status(http.StatusNotFound)
+		return s.missing, c.Request.URL.Path
+	}
+
+	base := s.conf.SitePath
 	rcPrefix := path.Join(base, resource)
 
 	// Assume we'll find a resource
 	c.Status(http.StatusOK)
 
-	for suffix, renderer := range resourceRenderer {
+	for suffix, renderer := range s.renderers {
 		rcPath := rcPrefix + "." + suffix
 		if fileExists(rcPath) {
 			return renderer, rcPath
@@ -82,7 +146,7 @@ func FindResourceFile(c *gin.Context, resource string) (resource.Renderer, strin
 	// We didn't find a resource
 	c.Status(http.StatusNotFound)
 
-	return &missingRenderer, resource
+	return s.missing, resource
 }
 
 func fileExists(path string) bool {
@@ -25,19 +25,33 @@ import (
 type PingResponse struct {
 	Timestamp int64
 	ClientIp  string
+	Sites     []SiteStatus `yaml:",omitempty"`
 }
 
-func AttachPing(d *Dispatcher) {
-	d.engine.GET("/ping", Ping)
+type SiteStatus struct {
+	Name    string
+	Hosts   []string
+	Indexed bool
+	Pages   int
+	Built   string `yaml:",omitempty"`
+}
+
+func AttachPing(r gin.IRoutes) {
+	r.GET("/ping", Ping)
 }
 
 func Ping(c *gin.Context) {
+	noteClass(c, ClassPing)
 
 	r := PingResponse{
 		Timestamp: time.Now().UnixNano() / 1000,
 		ClientIp:  c.ClientIP(),
 	}
 
+	if d := ContextDispatcher(c); d != nil {
+		r.Sites = d.siteStatus()
+	}
+
 	id := c.GetHeader("X-Ping-Id")
 	if id == "" {
 		id = fmt.Sprintf("%d", r.Timestamp)
@@ -46,3 +60,25 @@ func Ping(c *gin.Context) {
 	c.Header("X-Ping-Id", id)
 	c.YAML(200, r)
 }
+
+func (d *Dispatcher) siteStatus() []SiteStatus {
+	sites := d.Sites()
+	status := make([]SiteStatus, 0, len(sites))
+
+	for _, hs := range sites {
+		is := hs.Site.IndexStatus()
+		ss := SiteStatus{
+			Name:    hs.Name,
+			Hosts:   hs.Hosts,
+			Indexed: is.Indexed,
+			Pages:   is.Pages,
+		}
+		if is.Indexed {
+			ss.Built = is.Built.Format(time.RFC3339)
+		}
+
+		status = append(status, ss)
+	}
+
+	return status
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/auth"
+	"net/http"
+	"time"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// Route classes, for grouping requests in metrics
+const (
+	ClassPage     = "page"
+	ClassAsset    = "asset"
+	ClassToc      = "toc"
+	ClassPing     = "ping"
+	ClassApi      = "api"
+	ClassTaxonomy = "taxonomy"
+	ClassFeed     = "feed"
+	ClassSitemap  = "sitemap"
+	ClassOther    = "other"
+)
+
+// RequestRecord collects the details of a request that are only known to the
+// site handling it. It travels on the request context, so it is shared by the
+// dispatcher and the site engines.
+type RequestRecord struct {
+	Id      string
+	Site    string
+	Class   string
+	User    string
+	Page    string
+	Mode    string
+	Render  time.Duration
+	Latency time.Duration
+}
+
+// RequestObserver is notified once a tracked request has been handled.
+type RequestObserver func(c *gin.Context, rec *RequestRecord)
+
+type requestRecordKey struct{}
+
+func RequestRecordFrom(r *http.Request) *RequestRecord {
+	rec, _ := r.Context().Value(requestRecordKey{}).(*RequestRecord)
+
+	return rec
+}
+
+// TrackRequests attaches a RequestRecord to each request, and passes the
+// completed record to the observers.
+func TrackRequests(observers ...RequestObserver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		rec := &RequestRecord{Id: requestId(c), Class: ClassOther}
+		c.Header(RequestIdHeader, rec.Id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestRecordKey{}, rec))
+
+		c.Next()
+
+		rec.Latency = time.Since(start)
+		for _, o := range observers {
+			o(c, rec)
+		}
+	}
+}
+
+func noteUser(c *gin.Context, u *auth.User) {
+	auth.SetContextUser(c, u)
+
+	if rec := RequestRecordFrom(c.Request); rec != nil {
+		rec.User = u.Name
+	}
+}
+
+func noteClass(c *gin.Context, class string) {
+	if rec := RequestRecordFrom(c.Request); rec != nil {
+		rec.Class = class
+	}
+}
+
+func noteSite(c *gin.Context, name string) {
+	if rec := RequestRecordFrom(c.Request); rec != nil {
+		rec.Site = name
+	}
+}
+
+func notePage(c *gin.Context, class string, page string, mode string, render time.Duration) {
+	if rec := RequestRecordFrom(c.Request); rec != nil {
+		rec.Class = class
+		rec.Page = page
+		rec.Mode = mode
+		rec.Render = render
+	}
+}
+
+func requestId(c *gin.Context) string {
+	id := c.GetHeader(RequestIdHeader)
+	if validRequestId(id) {
+		return id
+	}
+
+	buf := make([]byte, 8)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -18,6 +18,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
@@ -25,47 +26,84 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 )
 
 type Dispatcher struct {
-	engine     *gin.Engine
-	conf       *config.Values
-	bindAddr   *net.TCPAddr
-	clientAddr string
-	server     *http.Server
+	engine   *gin.Engine
+	routes   *gin.RouterGroup
+	open     *gin.RouterGroup
+	conf     *config.Values
+	lock     sync.RWMutex
+	sites    []*HostedSite
+	bindAddr net.Addr
+	server   *http.Server
+
+	tlsBindAddr net.Addr
+	tlsServer   *http.Server
+
+	started time.Time
+
+	metrics       *serverMetrics
+	admin         *gin.Engine
+	adminBindAddr net.Addr
+	adminServer   *http.Server
 }
 
 func CreateDispatcher(v *config.Values) *Dispatcher {
 
 	// Create the Gin Engine
 	gin.SetMode(gin.ReleaseMode)
-	e := gin.Default()
+	e := gin.New()
 	log.Infof("Gin startup complete")
 
 	d := Dispatcher{
-		engine: e,
-		conf:   v,
+		engine:  e,
+		conf:    v,
+		started: time.Now(),
+	}
+	d.metrics = d.createMetrics()
+
+	observers := []RequestObserver{d.metrics.observe}
+	if v.AccessLogFormat != config.AccessLogOff {
+		observers = append(observers, AccessLog(v.AccessLogFormat, v.AccessLogFields, v.Logs()))
 	}
 
+	e.Use(gin.Recovery())
+	e.Use(TrackRequests(observers...))
+
 	// Attach config via middleware
 	e.Use(AddContextConfiguration(v))
+	e.Use(AddContextDispatcher(&d))
 
-	e.Use(gin.Recovery())
+	// Mount all routes under the site base path. Health probes, ping and
+	// metrics come from orchestration, load balancers and monitoring, so
+	// they skip authentication.
+	d.open = e.Group(v.BaseUrl() + "/")
+	d.routes = e.Group(v.BaseUrl()+"/", d.Authenticate)
+	AttachHealth(d.open)
 
 	d.AttachUtility()
-	d.AttachPages()
 
 	return &d
 }
 
 func (d *Dispatcher) AttachUtility() {
-	AttachPing(d)
-}
+	AttachPing(d.open)
 
-func (d *Dispatcher) AttachPages() {
-	AttachIndex(d)
-	AttachToc(d)
-	AttachPageHandler(d)
+	if d.conf.AdminListen != "" {
+		d.admin = gin.New()
+		d.admin.Use(gin.Recovery())
+		d.admin.Use(AddContextConfiguration(d.conf))
+		d.admin.Use(AddContextDispatcher(d))
+
+		AttachMetrics(d.admin)
+	} else {
+		AttachMetrics(d.open)
+	}
+
+	d.attachAdmin()
 }
 
 func (d *Dispatcher) AttachMiddleware() {
@@ -73,8 +111,94 @@ func (d *Dispatcher) AttachMiddleware() {
 }
 
 func (d *Dispatcher) Start() {
+	var plain, secure net.Listener
+
+	if d.conf.InheritListeners {
+		inherited, err := InheritListeners()
+		if err != nil {
+			log.Fatalf("Failed to take over inherited listeners: %s", err)
+		}
+		if len(inherited) > 0 {
+			log.Infof("Inherited %d listener(s) from the service manager", len(inherited))
+			plain, secure = assignInherited(inherited, d.conf.TlsEnabled())
+		}
+	}
+
+	if plain == nil {
+		plain = d.listen()
+	}
+	d.bindAddr = plain.Addr()
+	log.Infof("Listening on: %s", describeAddr(d.bindAddr))
+
+	var handler http.Handler = d.engine
+
+	if d.conf.TlsEnabled() {
+		certs, err := CreateCertificateStore(d.conf)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %s", err)
+		}
+
+		if secure == nil {
+			secure = d.listenTcp(d.conf.ListenIp, d.conf.TlsPort)
+		}
+		d.tlsBindAddr = secure.Addr()
+		log.Infof("Listening for TLS on: %s", describeAddr(d.tlsBindAddr))
+
+		tlsConf := &tls.Config{
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+
+		d.tlsServer = &http.Server{Handler: d.engine, TLSConfig: tlsConf}
+		go serve(d.tlsServer, tls.NewListener(secure, tlsConf))
+
+		if d.conf.TlsRedirect {
+			tlsPort := int(d.conf.TlsPort)
+			if tcpAddr, ok := d.tlsBindAddr.(*net.TCPAddr); ok {
+				tlsPort = tcpAddr.Port
+			}
+
+			log.Infof("Redirecting unencrypted requests to port: %d", tlsPort)
+			handler = RedirectToTls(tlsPort)
+		}
+	} else if secure != nil {
+		log.Warnf("Ignoring inherited TLS listener because TLS is not configured")
+		secure.Close()
+	}
+
+	d.server = &http.Server{Handler: handler}
+	go serve(d.server, plain)
 
-	listenAddr := fmt.Sprintf("%s:%d", d.conf.ListenIp.String(), d.conf.ListenPort)
+	if d.admin != nil {
+		l, err := listenAddress(d.conf.AdminListen, d.conf.SocketMode)
+		if err != nil {
+			log.Fatalf("Failed to set up admin socket: %s", err)
+		}
+		d.adminBindAddr = l.Addr()
+		log.Infof("Serving admin endpoints on: %s", describeAddr(d.adminBindAddr))
+
+		d.adminServer = &http.Server{Handler: d.admin}
+		go serve(d.adminServer, l)
+	}
+}
+
+func (d *Dispatcher) listen() net.Listener {
+	if d.conf.ListenSocket == "" {
+		return d.listenTcp(d.conf.ListenIp, d.conf.ListenPort)
+	}
+
+	log.Infof("Starting server on: %s", d.conf.ListenAddress())
+
+	l, err := listenUnix(d.conf.ListenSocket, d.conf.SocketMode)
+	if err != nil {
+		log.Fatalf("Failed to set up server socket: %s", err)
+	}
+
+	return l
+}
+
+func (d *Dispatcher) listenTcp(ip net.IP, port uint16) net.Listener {
+	listenAddr := fmt.Sprintf("%s:%d", ip.String(), port)
 	log.Infof("Starting server on: %s", listenAddr)
 
 	// Start a listener
@@ -83,30 +207,28 @@ func (d *Dispatcher) Start() {
 		log.Fatalf("Failed to set up server socket: %s", err)
 	}
 	lAddr := l.Addr()
-	d.clientAddr = lAddr.String()
 
-	var addrValid bool
-	d.bindAddr, addrValid = lAddr.(*net.TCPAddr)
+	bindAddr, addrValid := lAddr.(*net.TCPAddr)
 	if !addrValid {
 		log.Fatalf("Abnormal binding issue: Listener address is not a TCP address (%T)", lAddr)
 	}
 
 	// Report address binding
-	listenIp := d.bindAddr.IP.String()
+	listenIp := bindAddr.IP.String()
 	if listenIp == "::" {
 		listenIp = "<all>"
 	}
 	log.Infof("Listening on interface: %s", listenIp)
-	log.Infof("Listening on port: %d", d.bindAddr.Port)
+	log.Infof("Listening on port: %d", bindAddr.Port)
 
-	go func() {
-		server := http.Server{Handler: d.engine}
-		d.server = &server
-		err := d.server.Serve(l)
-		if err != nil {
-			log.Fatalf("Error while trying to start server: %s", err)
-		}
-	}()
+	return l
+}
+
+func serve(server *http.Server, l net.Listener) {
+	err := server.Serve(l)
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Error while trying to start server: %s", err)
+	}
 }
 
 func (d *Dispatcher) Shutdown() {
@@ -114,11 +236,35 @@ func (d *Dispatcher) Shutdown() {
 	if err != nil {
 		log.Warnf("Error while trying to shut down: %s", err)
 	}
+
+	if d.tlsServer != nil {
+		err = d.tlsServer.Shutdown(context.Background())
+		if err != nil {
+			log.Warnf("Error while trying to shut down TLS server: %s", err)
+		}
+	}
+
+	if d.adminServer != nil {
+		err = d.adminServer.Shutdown(context.Background())
+		if err != nil {
+			log.Warnf("Error while trying to shut down admin server: %s", err)
+		}
+	}
 }
 
 func (d *Dispatcher) ServerUrl() url.URL {
-	return url.URL{
-		Scheme: "http",
-		Host:   d.clientAddr,
+	if d.tlsServer != nil {
+		return addrUrl("https", d.tlsBindAddr)
 	}
+
+	return d.HttpUrl()
+}
+
+func (d *Dispatcher) HttpUrl() url.URL {
+	return addrUrl("http", d.bindAddr)
+}
+
+// AdminUrl is the address of the separate admin listener, if there is one.
+func (d *Dispatcher) AdminUrl() url.URL {
+	return addrUrl("http", d.adminBindAddr)
 }
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/auth"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/resource"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"strings"
+)
+
+const contextSite = "mdsite-site"
+
+// Site is a single, self-contained doc site. It owns its configuration,
+// index and renderers, so several sites can be served from one process.
+type Site struct {
+	conf      *config.Values
+	indexer   *site.Indexer
+	renderers map[string]resource.Renderer
+	pages     *resource.Pages
+	missing   resource.Renderer
+	engine    *gin.Engine
+	htpasswd  *auth.Htpasswd
+	jwt       *auth.JwtVerifier
+	acl       *auth.AccessList
+}
+
+func NewSite(v *config.Values, sc config.Site) (*Site, error) {
+	conf := *v
+	conf.SiteConfig = sc
+
+	s := Site{
+		conf:      &conf,
+		indexer:   site.NewIndexer(&conf),
+		renderers: resource.DefaultRenderers(),
+		missing:   resource.MissingResource{},
+	}
+
+	s.pages = resource.NewPages(&conf, s.renderers)
+	s.indexer.Scan = s.pages.ScanLinks
+
+	err := s.loadAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	s.engine = s.createEngine()
+
+	return &s, nil
+}
+
+func (s *Site) createEngine() *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	e := gin.New()
+
+	e.Use(gin.Recovery())
+	e.Use(AddContextConfiguration(s.conf))
+	e.Use(AddContextSite(s))
+	e.Use(s.Authenticate)
+	e.Use(s.Authorize)
+
+	base := s.conf.BaseUrl()
+	if base != "" {
+		log.Infof("Serving site under base path: %s", base)
+	}
+	routes := e.Group(base + "/")
+
+	AttachIndex(routes)
+	AttachToc(routes)
+	AttachLinkReport(routes)
+	AttachApi(routes)
+	AttachFeeds(routes)
+	AttachSitemap(routes)
+	s.AttachTaxonomies(routes)
+	AttachPageHandler(e)
+
+	return e
+}
+
+func (s *Site) RegisterRenderer(suffix string, renderer resource.Renderer) {
+	s.renderers[suffix] = renderer
+}
+
+func (s *Site) Config() *config.Values {
+	return s.conf
+}
+
+func (s *Site) Index() *site.PageIndex {
+	return s.indexer.Index()
+}
+
+func (s *Site) IndexStatus() site.IndexStatus {
+	return s.indexer.Status()
+}
+
+func (s *Site) ReIndex() (*site.PageIndex, error) {
+	return s.indexer.ReIndex()
+}
+
+// Reload creates a new version of the site from its config files, carrying
+// over any registered renderers, and builds its index. The site itself is
+// left untouched, so it can keep serving if the reload fails.
+func (s *Site) Reload() (*Site, error) {
+	v := *s.conf
+
+	sc, err := config.LoadSiteConfig(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := NewSite(&v, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	for suffix, renderer := range s.renderers {
+		ns.RegisterRenderer(suffix, renderer)
+	}
+
+	_, err = ns.ReIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	return ns, nil
+}
+
+func (s *Site) Handler() http.Handler {
+	return s.engine
+}
+
+// absoluteUrl makes a link absolute, using the origin of the site baseUrl if
+// it has one, or else the origin of the request.
+func (s *Site) absoluteUrl(c *gin.Context, u string) string {
+	if strings.Contains(u, "://") {
+		return u
+	}
+
+	origin := s.conf.SiteOrigin()
+	if origin == "" {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		origin = scheme + "://" + c.Request.Host
+	}
+
+	return origin + u
+}
+
+func AddContextSite(s *Site) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextSite, s)
+	}
+}
+
+func ContextSite(c *gin.Context) *Site {
+	si := c.Value(contextSite)
+
+	s, ok := si.(*Site)
+	if ok {
+		return s
+	}
+
+	log.Fatalf("No site attached to context: %s", c.FullPath())
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testSiteValues(s *suite.Suite, siteName string) *config.Values {
+	v := config.Create()
+	v.EnableTestMode()
+
+	cwd, cwdErr := os.Getwd()
+	s.Require().NoError(cwdErr)
+
+	basedir := filepath.Dir(filepath.Dir(cwd))
+	testdataPath := filepath.Join(basedir, "testdata/sites/"+siteName)
+	v.ConfigPath = filepath.Join(testdataPath, "config")
+	v.SitePath = filepath.Join(testdataPath, "site")
+
+	return v
+}
+
+func loadTestSite(s *suite.Suite, v *config.Values) *Site {
+	sc, err := config.LoadSiteConfig(v)
+	s.Require().NoError(err)
+
+	st, err := NewSite(v, sc)
+	s.Require().NoError(err)
+
+	return st
+}
+
+type SiteTestSuite struct {
+	suite.Suite
+}
+
+func TestSiteTestSuite(t *testing.T) {
+	suite.Run(t, new(SiteTestSuite))
+}
+
+func (t *SiteTestSuite) TestSite_Handler() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "test01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/toc").Expect().Status(http.StatusOK).Body().Contains("/info/deep-file")
+	e.GET("/sample-01").Expect().Status(http.StatusOK).Body().Contains("Sample Markdown File")
+	e.GET("/no-such-page").Expect().Status(http.StatusNotFound)
+}
+
+func (t *SiteTestSuite) TestSite_MultipleInstances() {
+	st1 := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "test01"))
+
+	v2 := testSiteValues(&t.Suite, "fail03")
+	v2.BasePath = "/other"
+	st2 := loadTestSite(&t.Suite, v2)
+
+	server1 := httptest.NewServer(st1.Handler())
+	defer server1.Close()
+	server2 := httptest.NewServer(st2.Handler())
+	defer server2.Close()
+
+	e1 := httpexpect.New(t.T(), server1.URL)
+	e2 := httpexpect.New(t.T(), server2.URL)
+
+	e1.GET("/toc").Expect().Status(http.StatusOK).Body().NotContains("/other/file")
+	e2.GET("/other/toc").Expect().Status(http.StatusOK).Body().Contains("/other/file")
+	e2.GET("/other/sample-01").Expect().Status(http.StatusNotFound)
+
+	t.Len(st1.Index().Pages, 4)
+	t.Len(st2.Index().Pages, 1)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/site"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	SitemapPath = site.SitemapPath
+	RobotsPath  = site.RobotsPath
+)
+
+// AttachSitemap serves the sitemap of the pages anyone can read, along with
+// robots.txt. Sites with more pages than fit in one sitemap are split into
+// /sitemap/<n>.xml, and /sitemap.xml lists them instead.
+func AttachSitemap(r gin.IRoutes) {
+	r.GET(SitemapPath, SitemapHandler)
+	r.GET(site.SitemapPartPath+"/:part", SitemapHandler)
+	r.GET(RobotsPath, RobotsHandler)
+}
+
+func SitemapHandler(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassSitemap)
+
+	// The same for everyone, so signed in crawlers do not publish
+	// restricted pages
+	pages := s.RenderedPages(s.PublicIndex()).SitemapPages()
+	size := s.conf.SiteConfig.Sitemap.Size
+	parts := (len(pages) + size - 1) / size
+
+	var err error
+	part := c.Param("part")
+	switch {
+	case part == "" && parts > 1:
+		c.Header("Content-Type", gin.MIMEXML)
+		err = site.WriteSitemapIndex(c.Writer, s.sitemapRefs(c, pages, parts))
+	case part == "":
+		c.Header("Content-Type", gin.MIMEXML)
+		err = site.WriteSitemap(c.Writer, s.sitemapUrls(c, pages))
+	default:
+		n, convErr := strconv.Atoi(strings.TrimSuffix(part, ".xml"))
+		if convErr != nil || !strings.HasSuffix(part, ".xml") || parts < 2 || n < 1 || n > parts {
+			Page(c)
+			return
+		}
+
+		c.Header("Content-Type", gin.MIMEXML)
+		err = site.WriteSitemap(c.Writer, s.sitemapUrls(c, sitemapPart(pages, size, n)))
+	}
+
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// sitemapPart picks the pages listed in one file of a split sitemap,
+// counting from 1.
+func sitemapPart(pages []*site.PageEntry, size int, n int) []*site.PageEntry {
+	end := n * size
+	if end > len(pages) {
+		end = len(pages)
+	}
+
+	return pages[(n-1)*size : end]
+}
+
+func (s *Site) sitemapUrls(c *gin.Context, pages []*site.PageEntry) []site.SitemapUrl {
+	urls := make([]site.SitemapUrl, len(pages))
+	for n, p := range pages {
+		urls[n] = site.SitemapUrl{
+			Loc:      s.absoluteUrl(c, p.Url),
+			LastMod:  site.LastMod(p.Modified),
+			Priority: p.Sitemap.Priority,
+		}
+	}
+
+	return urls
+}
+
+func (s *Site) sitemapRefs(c *gin.Context, pages []*site.PageEntry, parts int) []site.SitemapRef {
+	size := s.conf.SiteConfig.Sitemap.Size
+
+	refs := make([]site.SitemapRef, parts)
+	for n := range refs {
+		ref := &refs[n]
+		ref.Loc = s.absoluteUrl(c, s.conf.SiteUrl(fmt.Sprintf("/sitemap/%d.xml", n+1)))
+
+		// The most recent change to any page in the file
+		for _, p := range sitemapPart(pages, size, n+1) {
+			if lastMod := site.LastMod(p.Modified); lastMod > ref.LastMod {
+				ref.LastMod = lastMod
+			}
+		}
+	}
+
+	return refs
+}
+
+// RobotsHandler serves the robots.txt of the site, which leads crawlers to
+// the sitemap unless it already names one.
+func RobotsHandler(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassSitemap)
+
+	robots := s.conf.SiteConfig.Robots
+	if !strings.Contains(strings.ToLower(robots), "sitemap:") {
+		robots = strings.TrimRight(robots, "\n") + "\n\nSitemap: " + s.absoluteUrl(c, s.conf.SiteUrl(SitemapPath)) + "\n"
+	}
+
+	c.String(http.StatusOK, "%s", strings.TrimLeft(robots, "\n"))
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type SitemapTestSuite struct {
+	suite.Suite
+}
+
+func TestSitemapTestSuite(t *testing.T) {
+	suite.Run(t, new(SitemapTestSuite))
+}
+
+// splitSite loads a site with a sitemap split across two files. Files are
+// given known times, since checkouts do not keep them.
+func (t *SitemapTestSuite) splitSite() *Site {
+	v := testSiteValues(&t.Suite, "sitemap01")
+
+	times := map[string]time.Time{
+		"index.md":       time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC),
+		"guide.md":       time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC),
+		"ops/runbook.md": time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for file, mtime := range times {
+		t.Require().NoError(os.Chtimes(filepath.Join(v.SitePath, file), mtime, mtime))
+	}
+
+	return loadTestSite(&t.Suite, v)
+}
+
+func (t *SitemapTestSuite) TestSitemapIndex() {
+	server := httptest.NewServer(t.splitSite().Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	r := e.GET("/sitemap.xml").Expect().
+		Status(http.StatusOK)
+	r.Header("Content-Type").Equal("application/xml")
+	r.Body().
+		Contains(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`).
+		Contains(`<loc>` + server.URL + `/sitemap/1.xml</loc>` + "\n    <lastmod>2020-05-01T12:00:00Z</lastmod>").
+		Contains(`<loc>` + server.URL + `/sitemap/2.xml</loc>` + "\n    <lastmod>2020-06-01T08:00:00Z</lastmod>")
+
+	e.GET("/sitemap/1.xml").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`).
+		Contains(`<loc>` + server.URL + `/guide</loc>` + "\n    <lastmod>2020-05-01T12:00:00Z</lastmod>\n    <priority>0.5</priority>").
+		Contains(`<loc>` + server.URL + `/index</loc>`).
+		NotContains(`/ops/runbook`)
+
+	// Excluded pages and files that are not pages are left out
+	e.GET("/sitemap/2.xml").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<loc>` + server.URL + `/ops/runbook</loc>`).
+		NotContains(`/draft`).
+		NotContains(`/logo`)
+
+	e.GET("/sitemap/3.xml").Expect().
+		Status(http.StatusNotFound)
+	e.GET("/sitemap/one.xml").Expect().
+		Status(http.StatusNotFound)
+}
+
+func (t *SitemapTestSuite) TestSitemap() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "feed01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/sitemap.xml").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`).
+		Contains(`<loc>https://docs.example.com/dev/setup</loc>`).
+		Contains(`<loc>https://docs.example.com/ops/restart</loc>`).
+		NotContains(`<priority>`)
+
+	// Only split sitemaps have parts
+	e.GET("/sitemap/1.xml").Expect().
+		Status(http.StatusNotFound)
+}
+
+func (t *SitemapTestSuite) TestSitemap_Restricted() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "auth01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	// Even users who can read a restricted page don't see it listed
+	e.GET("/sitemap.xml").WithBasicAuth("alice", "password").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(server.URL + "/page</loc>").
+		NotContains("/security/incidents")
+}
+
+func (t *SitemapTestSuite) TestRobots() {
+	server := httptest.NewServer(t.splitSite().Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/robots.txt").Expect().
+		Status(http.StatusOK).
+		Body().
+		Equal("User-agent: *\nDisallow: /api/\n\nSitemap: " + server.URL + "/sitemap.xml\n")
+}
+
+func (t *SitemapTestSuite) TestRobots_Default() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "feed01"))
+
+	server := httptest.NewServer(st.Handler())
+	defer server.Close()
+	e := httpexpect.New(t.T(), server.URL)
+
+	e.GET("/robots.txt").Expect().
+		Status(http.StatusOK).
+		Body().
+		Equal("User-agent: *\nDisallow:\n\nSitemap: https://docs.example.com/sitemap.xml\n")
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/resource"
+	"github.com/zpxio/mdsite/pkg/site"
+	"html/template"
+	"net/http"
+)
+
+// AttachTaxonomies serves a list of terms at /<taxonomy>/, and the pages for
+// each term at /<taxonomy>/<term>, for every taxonomy of the site.
+func (s *Site) AttachTaxonomies(r gin.IRoutes) {
+	for _, tc := range s.conf.SiteConfig.Taxonomies {
+		h := s.TaxonomyHandler(tc)
+		r.GET("/"+tc.Path+"/", h)
+		r.GET("/"+tc.Path+"/:term", h)
+	}
+}
+
+func (s *Site) TaxonomyHandler(tc config.TaxonomyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		noteClass(c, ClassTaxonomy)
+
+		t, ok := s.VisibleIndex(c).Taxonomies[tc.Name]
+		if !ok {
+			// Not indexed yet
+			t = &site.Taxonomy{Name: tc.Name, Title: tc.Title}
+		}
+
+		data := site.TaxonomyPage{Taxonomy: t}
+		tpl := tc.ListTemplateFor(&s.conf.SiteConfig)
+
+		if slug := c.Param("term"); slug != "" {
+			term, ok := t.TermLookup[slug]
+			if !ok {
+				Page(c)
+				return
+			}
+
+			data.Term = term
+			tpl = tc.TermTemplateFor(&s.conf.SiteConfig)
+		}
+
+		content := bytes.Buffer{}
+		err := tpl.Execute(&content, data)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			c.Error(err)
+			return
+		}
+
+		c.Header("Content-Type", gin.MIMEHTML)
+		c.Status(http.StatusOK)
+
+		pd := resource.InitRenderData(c, c.Request.URL.Path)
+		pd.Title = s.conf.SiteConfig.Title
+		pd.BaseUrl = s.conf.BaseUrl()
+		pd.Content = template.HTML(content.String())
+
+		s.conf.SiteConfig.Global.PageTemplate.Execute(c.Writer, pd)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type TaxonomyTestSuite struct {
+	suite.Suite
+}
+
+func TestTaxonomyTestSuite(t *testing.T) {
+	suite.Run(t, new(TaxonomyTestSuite))
+}
+
+func (t *TaxonomyTestSuite) serve(basePath string) (*httpexpect.Expect, func()) {
+	v := testSiteValues(&t.Suite, "taxonomy01")
+	v.BasePath = basePath
+	st := loadTestSite(&t.Suite, v)
+
+	server := httptest.NewServer(st.Handler())
+
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t.T()),
+		Client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	})
+
+	return e, server.Close
+}
+
+func (t *TaxonomyTestSuite) TestTermList() {
+	e, done := t.serve("")
+	defer done()
+
+	e.GET("/tags/").Expect().
+		Status(http.StatusOK).
+		ContentType("text/html").
+		Body().
+		Contains("<title>Taxonomy01</title>").
+		Contains(`<h1>Tags</h1><ul class="taxonomy"><li><a href="/tags/billing">Billing</a> (2)</li><li><a href="/tags/money-out">Money Out</a> (1)</li></ul>`)
+
+	e.GET("/tags").Expect().
+		Status(http.StatusMovedPermanently).
+		Header("Location").Equal("/tags/")
+
+	e.GET("/audience/").Expect().
+		Status(http.StatusOK).
+		Body().Contains(`<a href="/audience/oncall">oncall</a> (2)`)
+}
+
+func (t *TaxonomyTestSuite) TestTermPages() {
+	e, done := t.serve("")
+	defer done()
+
+	e.GET("/tags/billing").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<h1>Tags: Billing</h1><ul class="page-list"><li><a href="/payouts">Payouts</a></li><li><a href="/refunds">Refunds</a></li></ul>`)
+
+	// Custom term template
+	e.GET("/services/payments").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<h1>payments service</h1><a href="/payouts">Payouts</a><a href="/refunds">Refunds</a>`)
+
+	e.GET("/tags/unknown").Expect().
+		Status(http.StatusNotFound)
+	e.GET("/service/payments").Expect().
+		Status(http.StatusNotFound)
+}
+
+func (t *TaxonomyTestSuite) TestBasePath() {
+	e, done := t.serve("/kb")
+	defer done()
+
+	e.GET("/kb/services/").Expect().
+		Status(http.StatusOK).
+		Body().
+		Contains(`<h1>Services</h1>`).
+		Contains(`<a href="/kb/services/search">search</a> (1)`)
+
+	e.GET("/kb/services/search").Expect().
+		Status(http.StatusOK).
+		Body().Contains(`<a href="/kb/search">Search</a>`)
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/apex/log"
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/util"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const certCheckInterval = 5 * time.Second
+
+// CertificateStore supplies the certificate for TLS handshakes, reloading it
+// from disk whenever the certificate or key file changes.
+type CertificateStore struct {
+	certWatch *util.FileWatch
+	keyWatch  *util.FileWatch
+	lastCheck time.Time
+
+	lock sync.RWMutex
+	cert *tls.Certificate
+}
+
+func LoadCertificateStore(certFile string, keyFile string) (*CertificateStore, error) {
+	s := CertificateStore{
+		certWatch: util.WatchFile(certFile),
+		keyWatch:  util.WatchFile(keyFile),
+		lastCheck: time.Now(),
+	}
+
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func StaticCertificateStore(cert *tls.Certificate) *CertificateStore {
+	return &CertificateStore{cert: cert}
+}
+
+func (s *CertificateStore) load() error {
+	cert, err := tls.LoadX509KeyPair(s.certWatch.Path, s.keyWatch.Path)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.cert = &cert
+	s.lock.Unlock()
+
+	log.Infof("Loaded TLS certificate: %s", s.certWatch.Path)
+
+	return nil
+}
+
+// Refresh reloads the certificate if either of its files has changed. A
+// certificate which fails to load leaves the previous one in service.
+func (s *CertificateStore) Refresh() {
+	if s.certWatch == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.lastCheck = time.Now()
+	certChanged := s.certWatch.Changed()
+	keyChanged := s.keyWatch.Changed()
+	s.lock.Unlock()
+
+	if !certChanged && !keyChanged {
+		return
+	}
+
+	err := s.load()
+	if err != nil {
+		log.Errorf("Failed to reload TLS certificate, keeping the previous one: %s", err)
+	}
+}
+
+func (s *CertificateStore) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.lock.RLock()
+	stale := s.certWatch != nil && time.Since(s.lastCheck) > certCheckInterval
+	s.lock.RUnlock()
+
+	if stale {
+		s.Refresh()
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.cert, nil
+}
+
+func CreateCertificateStore(v *config.Values) (*CertificateStore, error) {
+	if v.TlsSelfSigned {
+		log.Warn("Using a generated self-signed certificate. This is not suitable for production.")
+		cert, err := GenerateSelfSignedCertificate(selfSignedHosts())
+		if err != nil {
+			return nil, err
+		}
+
+		return StaticCertificateStore(cert), nil
+	}
+
+	return LoadCertificateStore(v.TlsCert, v.TlsKey)
+}
+
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	hostname, err := os.Hostname()
+	if err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+
+	return hosts
+}
+
+func GenerateSelfSignedCertificate(hosts []string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"mdsite development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+
+	return &cert, nil
+}
+
+// RedirectToTls sends every request to the same host and path on the
+// encrypted port.
+func RedirectToTls(tlsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		if tlsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(tlsPort))
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type TlsTestSuite struct {
+	suite.Suite
+	tempDir string
+}
+
+func TestTlsTestSuite(t *testing.T) {
+	suite.Run(t, new(TlsTestSuite))
+}
+
+func (t *TlsTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "mdsite-tls")
+	t.Require().NoError(err)
+	t.tempDir = dir
+}
+
+func (t *TlsTestSuite) TearDownTest() {
+	os.RemoveAll(t.tempDir)
+}
+
+func (t *TlsTestSuite) writeCertificate(hosts ...string) (string, string) {
+	cert, err := GenerateSelfSignedCertificate(hosts)
+	t.Require().NoError(err)
+
+	keyDer, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	t.Require().NoError(err)
+
+	certFile := filepath.Join(t.tempDir, "cert.pem")
+	keyFile := filepath.Join(t.tempDir, "key.pem")
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	t.Require().NoError(ioutil.WriteFile(certFile, certPem, 0600))
+	t.Require().NoError(ioutil.WriteFile(keyFile, keyPem, 0600))
+
+	return certFile, keyFile
+}
+
+func (t *TlsTestSuite) TestGenerateSelfSigned() {
+	cert, err := GenerateSelfSignedCertificate([]string{"localhost", "127.0.0.1"})
+
+	t.Require().NoError(err)
+	t.Require().NotNil(cert.Leaf)
+	t.Equal([]string{"localhost"}, cert.Leaf.DNSNames)
+	t.Len(cert.Leaf.IPAddresses, 1)
+	t.NoError(cert.Leaf.VerifyHostname("localhost"))
+}
+
+func (t *TlsTestSuite) TestCertificateStore_Reload() {
+	certFile, keyFile := t.writeCertificate("first.test")
+
+	store, err := LoadCertificateStore(certFile, keyFile)
+	t.Require().NoError(err)
+
+	c1, err := store.GetCertificate(nil)
+	t.Require().NoError(err)
+
+	t.writeCertificate("second.test")
+	future := time.Now().Add(time.Minute)
+	t.Require().NoError(os.Chtimes(certFile, future, future))
+	store.Refresh()
+
+	c2, err := store.GetCertificate(nil)
+	t.Require().NoError(err)
+	t.NotEqual(c1.Certificate[0], c2.Certificate[0])
+
+	leaf, err := x509.ParseCertificate(c2.Certificate[0])
+	t.Require().NoError(err)
+	t.Equal([]string{"second.test"}, leaf.DNSNames)
+}
+
+func (t *TlsTestSuite) TestCertificateStore_KeepsCertOnBadReload() {
+	certFile, keyFile := t.writeCertificate("first.test")
+
+	store, err := LoadCertificateStore(certFile, keyFile)
+	t.Require().NoError(err)
+
+	t.Require().NoError(ioutil.WriteFile(certFile, []byte("garbage"), 0600))
+	store.Refresh()
+
+	c, err := store.GetCertificate(nil)
+	t.Require().NoError(err)
+	t.NotNil(c)
+}
+
+func (t *TlsTestSuite) TestCertificateStore_Missing() {
+	_, err := LoadCertificateStore(filepath.Join(t.tempDir, "none.pem"), filepath.Join(t.tempDir, "none.key"))
+
+	t.Error(err)
+}
+
+func (t *TlsTestSuite) TestDispatcher_SelfSignedRedirect() {
+	conf := config.Create()
+	conf.EnableTestMode()
+	conf.ListenIp = config.DefaultIp
+	conf.TlsSelfSigned = true
+	conf.TlsRedirect = true
+
+	d := CreateDispatcher(conf)
+	d.Start()
+	defer d.Shutdown()
+
+	tlsUrl := d.ServerUrl()
+	t.Equal("https", tlsUrl.Scheme)
+
+	client := http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	httpUrl := d.HttpUrl()
+	httpUrl.Path = "/ping"
+	resp, err := client.Get(httpUrl.String())
+	t.Require().NoError(err)
+	resp.Body.Close()
+	t.Equal(http.StatusMovedPermanently, resp.StatusCode)
+	t.Contains(resp.Header.Get("Location"), "https://")
+	t.Contains(resp.Header.Get("Location"), "/ping")
+
+	tlsUrl.Path = "/ping"
+	resp, err = client.Get(tlsUrl.String())
+	t.Require().NoError(err)
+	resp.Body.Close()
+	t.Equal(http.StatusOK, resp.StatusCode)
+}
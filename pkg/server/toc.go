@@ -18,20 +18,20 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/zpxio/mdsite/pkg/config"
-	"github.com/zpxio/mdsite/pkg/site"
 	"net/http"
 )
 
-func AttachToc(d *Dispatcher) {
-	d.engine.GET("/toc", TableOfContents)
+func AttachToc(r gin.IRoutes) {
+	r.GET("/toc", TableOfContents)
 }
 
 func TableOfContents(c *gin.Context) {
+	s := ContextSite(c)
+	noteClass(c, ClassToc)
 
 	c.Header("Content-Type", gin.MIMEHTML)
 
-	err := config.Global().SiteConfig.Global.TocTemplate.Execute(c.Writer, site.Index())
+	err := s.Config().SiteConfig.Global.TocTemplate.Execute(c.Writer, s.VisibleIndex(c))
 	if err != nil {
 		c.Status(http.StatusInternalServerError)
 		c.Error(err)
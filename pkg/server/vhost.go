@@ -0,0 +1,185 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"github.com/zpxio/mdsite/pkg/config"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type HostedSite struct {
+	Name  string
+	Hosts []string
+	Site  *Site
+}
+
+// Host match priorities, from least to most specific
+const (
+	noMatch = iota
+	matchDefault
+	matchWildcard
+	matchExact
+)
+
+func matchHost(pattern string, host string) (int, int) {
+	switch {
+	case pattern == config.WildcardHost:
+		return matchDefault, 0
+	case strings.HasPrefix(pattern, "*."):
+		suffix := pattern[1:]
+		if strings.HasSuffix(host, suffix) {
+			return matchWildcard, len(suffix)
+		}
+	case pattern == host:
+		return matchExact, 0
+	}
+
+	return noMatch, 0
+}
+
+func requestHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// AttachHost serves a site for requests with a matching Host header. Hosts
+// may be exact names, wildcards such as "*.example.com", or "*" for the
+// default site.
+func (d *Dispatcher) AttachHost(name string, hosts []string, s *Site) {
+	log.Infof("Serving site [%s] for hosts: %s", name, strings.Join(hosts, ", "))
+
+	d.lock.Lock()
+	d.sites = append(d.sites, &HostedSite{
+		Name:  name,
+		Hosts: hosts,
+		Site:  s,
+	})
+	d.lock.Unlock()
+
+	d.engine.NoRoute(d.dispatchSite)
+}
+
+// AttachSite serves a site for every host.
+func (d *Dispatcher) AttachSite(s *Site) {
+	d.AttachHost("default", []string{config.WildcardHost}, s)
+}
+
+func (d *Dispatcher) SelectSite(r *http.Request) *HostedSite {
+	host := requestHost(r)
+
+	var best *HostedSite
+	bestRank, bestLen := noMatch, 0
+
+	for _, hs := range d.Sites() {
+		for _, pattern := range hs.Hosts {
+			rank, length := matchHost(pattern, host)
+			if rank > bestRank || (rank == bestRank && length > bestLen) {
+				best = hs
+				bestRank, bestLen = rank, length
+			}
+		}
+	}
+
+	return best
+}
+
+func (d *Dispatcher) dispatchSite(c *gin.Context) {
+	hs := d.SelectSite(c.Request)
+	if hs == nil {
+		c.String(http.StatusNotFound, "Unknown site: %s", requestHost(c.Request))
+		return
+	}
+
+	noteSite(c, hs.Name)
+	hs.Site.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// Sites lists the hosted sites. HostedSite values are never modified once
+// attached, so the list is safe to use while sites are being replaced.
+func (d *Dispatcher) Sites() []*HostedSite {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return append([]*HostedSite{}, d.sites...)
+}
+
+// FindSite looks up a hosted site by name.
+func (d *Dispatcher) FindSite(name string) *HostedSite {
+	for _, hs := range d.Sites() {
+		if hs.Name == name {
+			return hs
+		}
+	}
+
+	return nil
+}
+
+// replaceSite swaps in a new version of a hosted site, as long as the old
+// version is still current.
+func (d *Dispatcher) replaceSite(old *HostedSite, s *Site) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for i, hs := range d.sites {
+		if hs == old {
+			d.sites[i] = &HostedSite{
+				Name:  old.Name,
+				Hosts: old.Hosts,
+				Site:  s,
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// AttachSitesFile loads and indexes every site declared in a sites file.
+func (d *Dispatcher) AttachSitesFile(path string) error {
+	sf, err := config.LoadSitesFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, vs := range sf.Sites {
+		v := vs.Values(d.conf)
+
+		sc, err := config.LoadSiteConfig(v)
+		if err != nil {
+			return fmt.Errorf("site [%s]: %s", vs.Name, err)
+		}
+
+		s, err := NewSite(v, sc)
+		if err != nil {
+			return fmt.Errorf("site [%s]: %s", vs.Name, err)
+		}
+		s.Index()
+
+		d.AttachHost(vs.Name, vs.Hosts, s)
+	}
+
+	return nil
+}
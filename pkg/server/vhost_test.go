@@ -0,0 +1,108 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type VirtualHostTestSuite struct {
+	suite.Suite
+	dispatcher *Dispatcher
+	testServer *httptest.Server
+}
+
+func TestVirtualHostTestSuite(t *testing.T) {
+	suite.Run(t, new(VirtualHostTestSuite))
+}
+
+func (t *VirtualHostTestSuite) SetupSuite() {
+	cwd, err := os.Getwd()
+	t.Require().NoError(err)
+	sitesFile := filepath.Join(filepath.Dir(filepath.Dir(cwd)), "testdata/sites/hosts.yml")
+
+	conf := config.Create()
+	conf.EnableTestMode()
+
+	t.dispatcher = CreateDispatcher(conf)
+	t.Require().NoError(t.dispatcher.AttachSitesFile(sitesFile))
+
+	t.testServer = httptest.NewServer(t.dispatcher.engine)
+}
+
+func (t *VirtualHostTestSuite) TearDownSuite() {
+	t.testServer.Close()
+}
+
+func (t *VirtualHostTestSuite) TestSelectByHost() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	e.GET("/toc").WithHeader("Host", "docs.example.com").
+		Expect().Status(http.StatusOK).Body().Contains("/sample-01")
+	e.GET("/toc").WithHeader("Host", "team.docs.example.com:8080").
+		Expect().Status(http.StatusOK).Body().Contains("/sample-01")
+	e.GET("/toc").WithHeader("Host", "other.example.com").
+		Expect().Status(http.StatusOK).Body().Contains("/file")
+	e.GET("/sample-01").WithHeader("Host", "other.example.com").
+		Expect().Status(http.StatusNotFound)
+}
+
+func (t *VirtualHostTestSuite) TestPing_SiteStatus() {
+	e := httpexpect.New(t.T(), t.testServer.URL)
+
+	body := e.GET("/ping").Expect().Status(http.StatusOK).Body()
+	body.Contains("name: primary")
+	body.Contains("name: fallback")
+	body.Contains("pages: 4")
+}
+
+func (t *VirtualHostTestSuite) TestMatchHost() {
+	rank, _ := matchHost("*.example.com", "a.example.com")
+	t.Equal(matchWildcard, rank)
+
+	rank, _ = matchHost("*.example.com", "example.com")
+	t.Equal(noMatch, rank)
+
+	rank, _ = matchHost("example.com", "example.com")
+	t.Equal(matchExact, rank)
+
+	rank, _ = matchHost("*", "anything")
+	t.Equal(matchDefault, rank)
+}
+
+func (t *VirtualHostTestSuite) TestUnknownHost() {
+	conf := config.Create()
+	conf.EnableTestMode()
+
+	d := CreateDispatcher(conf)
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "test01"))
+	d.AttachHost("only", []string{"only.example.com"}, st)
+
+	server := httptest.NewServer(d.engine)
+	defer server.Close()
+
+	e := httpexpect.New(t.T(), server.URL)
+	e.GET("/toc").WithHeader("Host", "only.example.com").Expect().Status(http.StatusOK)
+	e.GET("/toc").WithHeader("Host", "else.example.com").Expect().Status(http.StatusNotFound)
+}
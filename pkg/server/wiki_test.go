@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/resource"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type WikiTestSuite struct {
+	suite.Suite
+}
+
+func TestWikiTestSuite(t *testing.T) {
+	suite.Run(t, new(WikiTestSuite))
+}
+
+func (t *WikiTestSuite) serve(st *Site) (*httpexpect.Expect, func()) {
+	server := httptest.NewServer(st.Handler())
+
+	return httpexpect.New(t.T(), server.URL), server.Close
+}
+
+func (t *WikiTestSuite) TestWikiLinks() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "wiki01"))
+
+	e, done := t.serve(st)
+	defer done()
+
+	body := e.GET("/welcome").Expect().
+		Status(http.StatusOK).
+		Body()
+
+	body.Contains(`<a href="/setup-guide" class="` + resource.WikiLinkClass + `">Setup Guide</a>`)
+	body.Contains(`<a href="/setup-guide" class="` + resource.WikiLinkClass + `">the setup</a>`)
+	body.Contains(`<a href="/setup-guide#install-steps" class="` + resource.WikiLinkClass + `">Setup Guide#Install Steps</a>`)
+	body.Contains(`<a href="#details" class="` + resource.WikiLinkClass + `">#Details</a>`)
+
+	// Unresolved and ambiguous names
+	body.Contains(`class="` + resource.WikiLinkClass + ` ` + resource.NewPageClass + `" title="New page: Future Page"`)
+	body.Contains(`title="New page: index"`)
+
+	// Code is left alone
+	body.Contains(`<code>[[Not A Link]]</code>`)
+	body.Contains(`[[Also Not A Link]]`)
+}
+
+func (t *WikiTestSuite) TestWikiEmbeds() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "wiki01"))
+
+	e, done := t.serve(st)
+	defer done()
+
+	body := e.GET("/welcome").Expect().
+		Status(http.StatusOK).
+		Body()
+
+	body.Contains(`<img class="` + resource.EmbedClass + `" src="/diagram.png" alt="diagram.png"/>`)
+	body.Contains(`<div class="` + resource.EmbedClass + `"><p>Shared <em>snippet</em> that links back to <a href="/welcome" class="` + resource.WikiLinkClass + `">welcome</a>.</p>`)
+	body.NotContains(`mdsite-embed-0-`)
+
+	// A page embedding itself gets a link instead
+	e.GET("/loop").Expect().
+		Status(http.StatusOK).
+		Body().Contains(`<a href="/loop" class="` + resource.WikiLinkClass + `">Loop</a>`)
+}
+
+func (t *WikiTestSuite) TestAmbiguousLinks() {
+	st := loadTestSite(&t.Suite, testSiteValues(&t.Suite, "wiki01"))
+	i, err := st.ReIndex()
+	t.Require().NoError(err)
+
+	t.Equal([]string{"index"}, i.PageLookup["/welcome"].AmbiguousLinks)
+	t.Empty(i.PageLookup["/setup-guide"].AmbiguousLinks)
+	t.Empty(i.PageLookup["/welcome"].BrokenLinks())
+}
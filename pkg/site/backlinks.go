@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+// LinkedPages lists the pages of the index that a page links to, leaving out
+// the page itself.
+func (i *PageIndex) LinkedPages(p *PageEntry) []*PageEntry {
+	var linked []*PageEntry
+	seen := make(map[string]bool)
+
+	for _, l := range p.Links {
+		if l.External || l.Broken || l.Target == p.Url || seen[l.Target] {
+			continue
+		}
+
+		if target, ok := i.PageLookup[l.Target]; ok {
+			seen[l.Target] = true
+			linked = append(linked, target)
+		}
+	}
+
+	return linked
+}
+
+// linkBacklinks builds the reverse link map from the internal links found on
+// every page, listing the pages that link to each page.
+func (i *PageIndex) linkBacklinks() {
+	i.Backlinks = make(map[string][]*PageEntry)
+
+	for _, p := range i.Pages {
+		for _, target := range i.LinkedPages(p) {
+			i.Backlinks[target.Url] = append(i.Backlinks[target.Url], p)
+		}
+	}
+}
+
+// BacklinksTo lists the pages that link to a page, in page order.
+func (i *PageIndex) BacklinksTo(p *PageEntry) []*PageEntry {
+	return i.Backlinks[p.Url]
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type BacklinksSuite struct {
+	suite.Suite
+}
+
+func TestBacklinksSuite(t *testing.T) {
+	suite.Run(t, new(BacklinksSuite))
+}
+
+func (s *BacklinksSuite) TestBacklinks() {
+	i := newPageIndex()
+	a := &PageEntry{Url: "/a"}
+	b := &PageEntry{Url: "/b"}
+	c := &PageEntry{Url: "/c"}
+	for _, p := range []*PageEntry{a, b, c} {
+		i.PageLookup[p.Url] = p
+		i.Pages = append(i.Pages, p)
+	}
+
+	a.Links = []Link{{Target: "/b"}, {Target: "/b"}, {Target: "/a"}, {Target: "/c", Broken: true}, {External: true}}
+	c.Links = []Link{{Target: "/b"}, {Target: "/a"}, {Target: "/gone"}}
+	i.linkBacklinks()
+
+	s.Equal([]*PageEntry{a, c}, i.BacklinksTo(b))
+	s.Equal([]*PageEntry{c}, i.BacklinksTo(a))
+	s.Empty(i.BacklinksTo(c))
+
+	f := i.Filter(func(p *PageEntry) bool {
+		return p != c
+	})
+	s.Equal([]*PageEntry{a}, f.BacklinksTo(b))
+	s.Empty(f.BacklinksTo(a))
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Feed formats
+const (
+	FeedAtom = "atom"
+	FeedRss  = "rss"
+	FeedJson = "json"
+)
+
+var FeedFormats = []string{FeedAtom, FeedRss, FeedJson}
+
+// Feed is a list of recently changed pages. Every URL in a feed is absolute,
+// so it can be read away from the site.
+type Feed struct {
+	Title   string
+	Link    string
+	FeedUrl string
+	Updated time.Time
+	Entries []FeedEntry
+}
+
+type FeedEntry struct {
+	Title   string
+	Url     string
+	Updated time.Time
+	Summary string
+	// The rendered page, if the feed includes it
+	Content string
+	Tags    []string
+}
+
+// RecentPages lists up to limit pages of the index, the most recently
+// updated first.
+func (i *PageIndex) RecentPages(limit int) []*PageEntry {
+	pages := append([]*PageEntry{}, i.Pages...)
+	sort.SliceStable(pages, func(a, b int) bool {
+		return pages[a].Updated().After(pages[b].Updated())
+	})
+
+	if len(pages) > limit {
+		pages = pages[:limit]
+	}
+
+	return pages
+}
+
+// Summarize takes the text of the first paragraph of rendered HTML, cut
+// short at a word boundary if it is longer than max characters.
+func Summarize(content []byte, max int) string {
+	text := strings.Builder{}
+	inParagraph := false
+
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for done := false; !done; {
+		switch z.Next() {
+		case html.ErrorToken:
+			done = true
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if string(name) == "p" {
+				inParagraph = true
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if string(name) == "p" {
+				// Skip paragraphs without any text, such as images
+				done = strings.TrimSpace(text.String()) != ""
+				inParagraph = false
+			}
+		case html.TextToken:
+			if inParagraph {
+				text.Write(z.Text())
+			}
+		}
+	}
+
+	summary := strings.Join(strings.Fields(text.String()), " ")
+	if utf8.RuneCountInString(summary) <= max {
+		return summary
+	}
+
+	cut := []rune(summary)[:max]
+	if n := strings.LastIndex(string(cut), " "); n > 0 {
+		return string(cut)[:n] + "…"
+	}
+
+	return string(cut) + "…"
+}
+
+// Write writes the feed in one of the feed formats.
+func (f *Feed) Write(w io.Writer, format string) error {
+	switch format {
+	case FeedAtom:
+		return f.WriteAtom(w)
+	case FeedRss:
+		return f.WriteRss(w)
+	case FeedJson:
+		return f.WriteJson(w)
+	}
+
+	return fmt.Errorf("unknown feed format: %s", format)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	Id      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	Id         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    *atomContent   `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// WriteAtom writes the feed as an Atom document.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	doc := atomFeed{
+		Title:   f.Title,
+		Id:      f.FeedUrl,
+		Updated: f.Updated.Format(time.RFC3339),
+		Author:  f.Title,
+		Links: []atomLink{
+			{Href: f.Link},
+			{Href: f.FeedUrl, Rel: "self"},
+		},
+	}
+
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			Title:   e.Title,
+			Id:      e.Url,
+			Link:    atomLink{Href: e.Url},
+			Updated: e.Updated.Format(time.RFC3339),
+			Summary: e.Summary,
+		}
+		if e.Content != "" {
+			entry.Content = &atomContent{Type: "html", Body: e.Content}
+		}
+		for _, tag := range e.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return writeXml(w, doc)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Guid        rssGuid  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description,omitempty"`
+	Content     string   `xml:"content:encoded,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Id          string `xml:",chardata"`
+}
+
+// WriteRss writes the feed as an RSS 2.0 document.
+func (f *Feed) WriteRss(w io.Writer) error {
+	doc := rssFeed{
+		Version: "2.0",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Title,
+			LastBuildDate: f.Updated.Format(time.RFC1123Z),
+		},
+	}
+
+	for _, e := range f.Entries {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.Url,
+			Guid:        rssGuid{IsPermaLink: true, Id: e.Url},
+			PubDate:     e.Updated.Format(time.RFC1123Z),
+			Description: e.Summary,
+			Content:     e.Content,
+			Categories:  e.Tags,
+		})
+	}
+
+	return writeXml(w, doc)
+}
+
+func writeXml(w io.Writer, doc interface{}) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	return enc.Encode(doc)
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageUrl string     `json:"home_page_url"`
+	FeedUrl     string     `json:"feed_url"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	Id           string   `json:"id"`
+	Url          string   `json:"url"`
+	Title        string   `json:"title"`
+	Summary      string   `json:"summary,omitempty"`
+	ContentHtml  string   `json:"content_html,omitempty"`
+	ContentText  string   `json:"content_text,omitempty"`
+	DateModified string   `json:"date_modified"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// WriteJson writes the feed as a JSON Feed 1.1 document.
+func (f *Feed) WriteJson(w io.Writer) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageUrl: f.Link,
+		FeedUrl:     f.FeedUrl,
+		Items:       []jsonItem{},
+	}
+
+	for _, e := range f.Entries {
+		item := jsonItem{
+			Id:           e.Url,
+			Url:          e.Url,
+			Title:        e.Title,
+			Summary:      e.Summary,
+			ContentHtml:  e.Content,
+			DateModified: e.Updated.Format(time.RFC3339),
+			Tags:         e.Tags,
+		}
+		// Every item needs some content
+		if item.ContentHtml == "" {
+			item.ContentText = e.Summary
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
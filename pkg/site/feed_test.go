@@ -0,0 +1,110 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type FeedSuite struct {
+	suite.Suite
+}
+
+func TestFeedSuite(t *testing.T) {
+	suite.Run(t, new(FeedSuite))
+}
+
+func (s *FeedSuite) feed() *Feed {
+	return &Feed{
+		Title:   "Docs & More",
+		Link:    "https://docs.example.com/",
+		FeedUrl: "https://docs.example.com/feed.atom",
+		Updated: time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC),
+		Entries: []FeedEntry{
+			{
+				Title:   "Restart",
+				Url:     "https://docs.example.com/ops/restart",
+				Updated: time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC),
+				Summary: "How to restart",
+				Content: "<p>Restart it</p>",
+				Tags:    []string{"oncall"},
+			},
+		},
+	}
+}
+
+func (s *FeedSuite) TestRecentPages() {
+	i := newPageIndex()
+	old := &PageEntry{Url: "/old", Modified: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	dated := &PageEntry{Url: "/dated", Modified: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Date: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	recent := &PageEntry{Url: "/recent", Modified: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}
+	i.Pages = []*PageEntry{old, dated, recent}
+
+	s.Equal([]*PageEntry{recent, dated, old}, i.RecentPages(5))
+	s.Equal([]*PageEntry{recent, dated}, i.RecentPages(2))
+
+	// The index keeps its own order
+	s.Equal([]*PageEntry{old, dated, recent}, i.Pages)
+}
+
+func (s *FeedSuite) TestSummarize() {
+	s.Equal("First paragraph.", Summarize([]byte("<h1>Title</h1><p>First\n  <em>paragraph</em>.</p><p>Second</p>"), 100))
+	s.Equal("Text", Summarize([]byte(`<p><img src="a.png"/></p><p>Text</p>`), 100))
+	s.Equal("A few short…", Summarize([]byte("<p>A few short words</p>"), 14))
+	s.Equal("Unbrok…", Summarize([]byte("<p>Unbroken</p>"), 6))
+	s.Equal("", Summarize([]byte("<h1>Only a title</h1>"), 100))
+}
+
+func (s *FeedSuite) TestWriteAtom() {
+	buf := bytes.Buffer{}
+	s.Require().NoError(s.feed().Write(&buf, FeedAtom))
+
+	s.Contains(buf.String(), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	s.Contains(buf.String(), `<title>Docs &amp; More</title>`)
+	s.Contains(buf.String(), `<updated>2020-03-01T09:30:00Z</updated>`)
+	s.Contains(buf.String(), `<content type="html">&lt;p&gt;Restart it&lt;/p&gt;</content>`)
+	s.Contains(buf.String(), `<category term="oncall"></category>`)
+}
+
+func (s *FeedSuite) TestWriteRss() {
+	buf := bytes.Buffer{}
+	s.Require().NoError(s.feed().Write(&buf, FeedRss))
+
+	s.Contains(buf.String(), `<lastBuildDate>Sun, 01 Mar 2020 09:30:00 +0000</lastBuildDate>`)
+	s.Contains(buf.String(), `<guid isPermaLink="true">https://docs.example.com/ops/restart</guid>`)
+	s.Contains(buf.String(), `<description>How to restart</description>`)
+	s.Contains(buf.String(), `<category>oncall</category>`)
+}
+
+func (s *FeedSuite) TestWriteJson() {
+	f := s.feed()
+	f.Entries[0].Content = ""
+
+	buf := bytes.Buffer{}
+	s.Require().NoError(f.Write(&buf, FeedJson))
+
+	s.Contains(buf.String(), `"version": "https://jsonfeed.org/version/1.1"`)
+	s.Contains(buf.String(), `"content_text": "How to restart"`)
+	s.NotContains(buf.String(), `"content_html"`)
+}
+
+func (s *FeedSuite) TestWrite_UnknownFormat() {
+	s.Error(s.feed().Write(&bytes.Buffer{}, "txt"))
+}
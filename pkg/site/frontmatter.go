@@ -0,0 +1,169 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"strings"
+	"time"
+)
+
+// FrontMatter is the YAML block at the start of a Markdown page, between
+// lines of "---".
+type FrontMatter struct {
+	Tags    StringList     `yaml:"tags"`
+	Date    Date           `yaml:"date"`
+	Summary string         `yaml:"summary"`
+	Sitemap SitemapOptions `yaml:"sitemap"`
+
+	// Every other key
+	Params map[string]interface{} `yaml:",inline"`
+}
+
+// Terms reads the values of a key as a list, from either a YAML list or a
+// comma separated string.
+func (fm *FrontMatter) Terms(key string) []string {
+	if key == "tags" {
+		return fm.Tags
+	}
+
+	switch value := fm.Params[key].(type) {
+	case nil:
+		return nil
+	case string:
+		return cleanList(strings.Split(value, ","))
+	case []interface{}:
+		items := make([]string, len(value))
+		for n, item := range value {
+			items[n] = fmt.Sprint(item)
+		}
+		return cleanList(items)
+	default:
+		return []string{fmt.Sprint(value)}
+	}
+}
+
+// StringList reads a YAML list of strings, or a single comma separated string.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items []string
+	if err := unmarshal(&items); err == nil {
+		*l = cleanList(items)
+		return nil
+	}
+
+	var single string
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+	*l = cleanList(strings.Split(single, ","))
+
+	return nil
+}
+
+// Date reads a date, with or without the time of day.
+type Date struct {
+	time.Time
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func (d *Date) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			d.Time = t
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid date: %s", value)
+}
+
+func cleanList(items []string) []string {
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
+
+var frontMatterFence = []byte("---")
+
+// SplitFrontMatter separates the front matter from the content of a page.
+// Pages without front matter are returned as they are.
+func SplitFrontMatter(data []byte) ([]byte, []byte) {
+	first := lineEnd(data, 0)
+	if !bytes.Equal(bytes.TrimSpace(data[:first]), frontMatterFence) {
+		return nil, data
+	}
+
+	for start := first; start < len(data); {
+		end := lineEnd(data, start)
+		line := bytes.TrimSpace(data[start:end])
+		if bytes.Equal(line, frontMatterFence) || bytes.Equal(line, []byte("...")) {
+			return data[first:start], data[end:]
+		}
+		start = end
+	}
+
+	// Never closed, so it is not front matter
+	return nil, data
+}
+
+// lineEnd finds the start of the line after the one starting at offset.
+func lineEnd(data []byte, offset int) int {
+	n := bytes.IndexByte(data[offset:], '\n')
+	if n < 0 {
+		return len(data)
+	}
+
+	return offset + n + 1
+}
+
+// ParseFrontMatter reads the front matter of a page, if it has any.
+func ParseFrontMatter(data []byte) (*FrontMatter, error) {
+	fm := FrontMatter{}
+
+	meta, _ := SplitFrontMatter(data)
+	if meta == nil {
+		return &fm, nil
+	}
+
+	err := yaml.Unmarshal(meta, &fm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fm, nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type FrontMatterSuite struct {
+	suite.Suite
+}
+
+func TestFrontMatterSuite(t *testing.T) {
+	suite.Run(t, new(FrontMatterSuite))
+}
+
+func (s *FrontMatterSuite) TestSplitFrontMatter() {
+	meta, body := SplitFrontMatter([]byte("---\ntags: [a]\n---\n# Title\n"))
+	s.Equal("tags: [a]\n", string(meta))
+	s.Equal("# Title\n", string(body))
+
+	meta, body = SplitFrontMatter([]byte("---\r\ntags: a\r\n...\r\nBody"))
+	s.Equal("tags: a\r\n", string(meta))
+	s.Equal("Body", string(body))
+
+	// Not closed
+	meta, body = SplitFrontMatter([]byte("---\nSome text\n"))
+	s.Nil(meta)
+	s.Equal("---\nSome text\n", string(body))
+
+	// No front matter
+	meta, body = SplitFrontMatter([]byte("# Title\n---\n"))
+	s.Nil(meta)
+	s.Equal("# Title\n---\n", string(body))
+
+	meta, body = SplitFrontMatter([]byte{})
+	s.Nil(meta)
+	s.Empty(body)
+}
+
+func (s *FrontMatterSuite) TestParseFrontMatter() {
+	fm, err := ParseFrontMatter([]byte("---\ntags: [one, \" two \"]\n---\n"))
+	s.Require().NoError(err)
+	s.Equal(StringList{"one", "two"}, fm.Tags)
+
+	fm, err = ParseFrontMatter([]byte("---\ntags: one, two,\n---\n"))
+	s.Require().NoError(err)
+	s.Equal(StringList{"one", "two"}, fm.Tags)
+
+	fm, err = ParseFrontMatter([]byte("No front matter"))
+	s.Require().NoError(err)
+	s.Empty(fm.Tags)
+
+	_, err = ParseFrontMatter([]byte("---\ntags: [unclosed\n---\n"))
+	s.Error(err)
+}
+
+func (s *FrontMatterSuite) TestParseFrontMatter_Date() {
+	fm, err := ParseFrontMatter([]byte("---\ndate: 2020-03-01\nsummary: Restarting the service\n---\n"))
+	s.Require().NoError(err)
+	s.Equal(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), fm.Date.Time)
+	s.Equal("Restarting the service", fm.Summary)
+
+	fm, err = ParseFrontMatter([]byte("---\ndate: \"2020-03-01 14:30\"\n---\n"))
+	s.Require().NoError(err)
+	s.Equal(time.Date(2020, 3, 1, 14, 30, 0, 0, time.UTC), fm.Date.Time)
+
+	fm, err = ParseFrontMatter([]byte("---\ndate: 2020-03-01T14:30:00+02:00\n---\n"))
+	s.Require().NoError(err)
+	s.True(time.Date(2020, 3, 1, 12, 30, 0, 0, time.UTC).Equal(fm.Date.Time))
+
+	_, err = ParseFrontMatter([]byte("---\ndate: last tuesday\n---\n"))
+	s.Error(err)
+}
+
+func (s *FrontMatterSuite) TestParseFrontMatter_Sitemap() {
+	fm, err := ParseFrontMatter([]byte("---\nsitemap:\n  priority: 0.8\n---\n"))
+	s.Require().NoError(err)
+	s.Equal(SitemapOptions{Priority: 0.8}, fm.Sitemap)
+
+	fm, err = ParseFrontMatter([]byte("---\nsitemap:\n  exclude: true\n---\n"))
+	s.Require().NoError(err)
+	s.True(fm.Sitemap.Exclude)
+}
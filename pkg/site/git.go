@@ -0,0 +1,196 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apex/log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitCommit is the last commit to change a page. Date is when the commit
+// was made, which is later than AuthorDate if it was rebased or applied
+// from a patch.
+type GitCommit struct {
+	Hash       string
+	Author     string
+	Email      string
+	Date       time.Time
+	AuthorDate time.Time
+	Message    string
+}
+
+// ShortHash is the abbreviated commit hash, as shown by git.
+func (c *GitCommit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+
+	return c.Hash
+}
+
+// Separators for the fields of each commit in the git log output
+const (
+	gitRecordSep = "\x1e"
+	gitFieldSep  = "\x1f"
+)
+
+// ReadGitHistory finds the last commit to change each file beneath a
+// directory, keyed by the slash separated path within the directory. The
+// repository may be at or above the directory. Directories outside of a git
+// repository have no history, which is not an error.
+func ReadGitHistory(dir string) (map[string]*GitCommit, error) {
+	inside, err := runGit(dir, "rev-parse", "--is-inside-work-tree")
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// Not a repository
+			return nil, nil
+		}
+		return nil, err
+	}
+	if strings.TrimSpace(inside) != "true" {
+		return nil, nil
+	}
+
+	out, err := runGit(dir, "-c", "core.quotePath=false", "log", "--relative", "--name-only", "--no-renames",
+		"--format="+gitRecordSep+"%H"+gitFieldSep+"%an"+gitFieldSep+"%ae"+gitFieldSep+"%cI"+gitFieldSep+"%aI"+gitFieldSep+"%s",
+		"--", ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitLog(out)
+}
+
+// GitHistory keeps the history read from a directory, and only reads it
+// again once HEAD has moved.
+type GitHistory struct {
+	lock    sync.Mutex
+	dir     string
+	head    string
+	commits map[string]*GitCommit
+}
+
+// Read finds the last commit to change each file beneath a directory, in the
+// same way as ReadGitHistory.
+func (h *GitHistory) Read(dir string) (map[string]*GitCommit, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	head, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		// Not a repository, or nothing committed yet
+		head = ""
+	}
+	head = strings.TrimSpace(head)
+	if head != "" && head == h.head && dir == h.dir {
+		return h.commits, nil
+	}
+
+	commits, err := ReadGitHistory(dir)
+	if err != nil {
+		return nil, err
+	}
+	h.dir, h.head, h.commits = dir, head, commits
+
+	return commits, nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	stderr := bytes.Buffer{}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		log.Debugf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return string(out), err
+}
+
+// parseGitLog reads the log, newest commit first, keeping the first commit
+// seen for each file.
+func parseGitLog(out string) (map[string]*GitCommit, error) {
+	history := make(map[string]*GitCommit)
+
+	for _, record := range strings.Split(out, gitRecordSep) {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		lines := strings.Split(record, "\n")
+		fields := strings.Split(lines[0], gitFieldSep)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected git log entry: %q", lines[0])
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, err
+		}
+		authorDate, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			return nil, err
+		}
+
+		c := &GitCommit{
+			Hash:       fields[0],
+			Author:     fields[1],
+			Email:      fields[2],
+			Date:       date,
+			AuthorDate: authorDate,
+			Message:    fields[5],
+		}
+
+		for _, file := range lines[1:] {
+			if file == "" {
+				continue
+			}
+			if _, ok := history[file]; !ok {
+				history[file] = c
+			}
+		}
+	}
+
+	return history, nil
+}
+
+// readGitHistory takes the time each page last changed from the history of
+// the site, in place of the file times. Pages that were never committed keep
+// their file times.
+func (i *PageIndex) readGitHistory(sitePath string, h *GitHistory) {
+	history, err := h.Read(sitePath)
+	if err != nil {
+		log.Warnf("Could not read git history of %s: %s", sitePath, err)
+		return
+	}
+
+	for _, p := range i.Pages {
+		if c, ok := history[filepath.ToSlash(p.Path)]; ok {
+			p.Commit = c
+			p.Modified = c.Date
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type GitSuite struct {
+	suite.Suite
+	repo string
+}
+
+func TestGitSuite(t *testing.T) {
+	suite.Run(t, new(GitSuite))
+}
+
+func (s *GitSuite) SetupTest() {
+	if _, err := exec.LookPath("git"); err != nil {
+		s.T().Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "mdsite-git")
+	s.Require().NoError(err)
+	s.repo = dir
+
+	s.git("", "", "init", "-q")
+	s.write("README.md")
+	s.write("docs/intro.md")
+	s.write("docs/ops/runbook.md")
+	s.git("Alice Admin", "2020-01-01T10:00:00Z", "add", ".")
+	s.git("Alice Admin", "2020-01-01T10:00:00Z", "commit", "-q", "-m", "Add the docs")
+
+	s.write("docs/ops/runbook.md")
+	s.write("docs/café.md")
+	s.git("Bob Builder", "2020-02-01T15:30:00+01:00", "add", ".")
+	s.git("Bob Builder", "2020-02-01T15:30:00+01:00", "commit", "-q", "-m", "Update the runbook")
+
+	// Never committed
+	s.write("docs/draft.md")
+}
+
+func (s *GitSuite) TearDownTest() {
+	os.RemoveAll(s.repo)
+}
+
+func (s *GitSuite) write(file string) {
+	path := filepath.Join(s.repo, file)
+	s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))
+
+	data, _ := ioutil.ReadFile(path)
+	s.Require().NoError(ioutil.WriteFile(path, append(data, "# Page\n"...), 0644))
+}
+
+func (s *GitSuite) git(author string, date string, args ...string) {
+	s.gitDates(author, date, date, args...)
+}
+
+// gitDates runs git with different author and commit dates, as a rebase
+// leaves them.
+func (s *GitSuite) gitDates(author string, authorDate string, commitDate string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.repo
+	cmd.Env = append(os.Environ(),
+		"HOME="+s.repo,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME="+author,
+		"GIT_AUTHOR_EMAIL=docs@example.com",
+		"GIT_AUTHOR_DATE="+authorDate,
+		"GIT_COMMITTER_NAME="+author,
+		"GIT_COMMITTER_EMAIL=docs@example.com",
+		"GIT_COMMITTER_DATE="+commitDate,
+	)
+
+	out, err := cmd.CombinedOutput()
+	s.Require().NoError(err, string(out))
+}
+
+func (s *GitSuite) TestReadGitHistory() {
+	history, err := ReadGitHistory(filepath.Join(s.repo, "docs"))
+	s.Require().NoError(err)
+
+	s.Len(history, 3)
+	s.NotContains(history, "README.md")
+	s.NotContains(history, "draft.md")
+
+	intro := history["intro.md"]
+	s.Require().NotNil(intro)
+	s.Equal("Alice Admin", intro.Author)
+	s.Equal("docs@example.com", intro.Email)
+	s.Equal("Add the docs", intro.Message)
+	s.True(time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC).Equal(intro.Date))
+	s.Len(intro.Hash, 40)
+	s.Equal(intro.Hash[:7], intro.ShortHash())
+
+	runbook := history["ops/runbook.md"]
+	s.Require().NotNil(runbook)
+	s.Equal("Bob Builder", runbook.Author)
+	s.Equal("Update the runbook", runbook.Message)
+	s.True(time.Date(2020, 2, 1, 14, 30, 0, 0, time.UTC).Equal(runbook.Date))
+
+	s.Equal(runbook, history["café.md"])
+}
+
+func (s *GitSuite) TestReadGitHistory_CommitDate() {
+	s.write("docs/intro.md")
+	s.git("Carol", "2020-03-01T09:00:00Z", "add", ".")
+	s.gitDates("Carol", "2019-06-01T12:00:00Z", "2020-03-01T09:00:00Z", "commit", "-q", "-m", "Rebased")
+
+	history, err := ReadGitHistory(filepath.Join(s.repo, "docs"))
+	s.Require().NoError(err)
+
+	intro := history["intro.md"]
+	s.Require().NotNil(intro)
+	s.True(time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC).Equal(intro.Date))
+	s.True(time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC).Equal(intro.AuthorDate))
+}
+
+func (s *GitSuite) TestGitHistory_Cached() {
+	docs := filepath.Join(s.repo, "docs")
+	h := GitHistory{}
+
+	first, err := h.Read(docs)
+	s.Require().NoError(err)
+	again, err := h.Read(docs)
+	s.Require().NoError(err)
+	s.True(first["intro.md"] == again["intro.md"], "history is read again with the same HEAD")
+
+	s.write("docs/intro.md")
+	s.git("Carol", "2020-03-01T09:00:00Z", "add", ".")
+	s.git("Carol", "2020-03-01T09:00:00Z", "commit", "-q", "-m", "Update the intro")
+
+	moved, err := h.Read(docs)
+	s.Require().NoError(err)
+	s.Equal("Carol", moved["intro.md"].Author)
+}
+
+func (s *GitSuite) TestReadGitHistory_NotRepository() {
+	dir, err := ioutil.TempDir("", "mdsite-nogit")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	history, err := ReadGitHistory(dir)
+	s.NoError(err)
+	s.Nil(history)
+}
+
+func (s *GitSuite) TestBuildIndex() {
+	v := config.Create()
+	v.SitePath = filepath.Join(s.repo, "docs")
+	v.ConfigPath = filepath.Join(s.repo, "config")
+	v.SiteConfig.Git.Enabled = true
+
+	i, err := BuildIndex(v)
+	s.Require().NoError(err)
+
+	runbook := i.PathLookup["ops/runbook.md"]
+	s.Require().NotNil(runbook)
+	s.Require().NotNil(runbook.Commit)
+	s.True(runbook.Commit.Date.Equal(runbook.Modified))
+	s.Equal("Last updated by Bob Builder on 1 February 2020", runbook.LastUpdated())
+
+	// Falls back to the file time
+	draft := i.PathLookup["draft.md"]
+	s.Require().NotNil(draft)
+	s.Nil(draft.Commit)
+	s.True(draft.Modified.After(time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC)))
+	s.Equal("Last updated on "+draft.Modified.Format("2 January 2006"), draft.LastUpdated())
+
+	v.SiteConfig.Git.Enabled = false
+	i, err = BuildIndex(v)
+	s.Require().NoError(err)
+	s.Nil(i.PathLookup["intro.md"].Commit)
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Graph is the network of links between the pages of a site.
+type Graph struct {
+	Title string      `json:"title"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	Id       string   `json:"id"`
+	Label    string   `json:"label"`
+	Path     string   `json:"path"`
+	Section  string   `json:"section"`
+	Tags     []string `json:"tags"`
+	Inbound  int      `json:"inbound"`
+	Outbound int      `json:"outbound"`
+}
+
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Graph builds the link graph of the index. Nodes are identified by the page
+// URL, and there is one edge for each pair of linked pages.
+func (i *PageIndex) Graph(title string) Graph {
+	g := Graph{
+		Title: title,
+		Nodes: make([]GraphNode, 0, len(i.Pages)),
+		Edges: []GraphEdge{},
+	}
+
+	inbound := make(map[string]int)
+	outbound := make(map[string]int)
+	for _, p := range i.Pages {
+		for _, target := range i.LinkedPages(p) {
+			g.Edges = append(g.Edges, GraphEdge{Source: p.Url, Target: target.Url})
+			outbound[p.Url]++
+			inbound[target.Url]++
+		}
+	}
+
+	for _, p := range i.Pages {
+		tags := p.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+
+		g.Nodes = append(g.Nodes, GraphNode{
+			Id:       p.Url,
+			Label:    p.Label,
+			Path:     filepath.ToSlash(p.Path),
+			Section:  p.Section(),
+			Tags:     tags,
+			Inbound:  inbound[p.Url],
+			Outbound: outbound[p.Url],
+		})
+	}
+
+	return g
+}
+
+// Orphans lists the pages that no other page links to, and that are not
+// listed in the page order either, so nothing leads to them but the contents.
+func (i *PageIndex) Orphans() []*PageEntry {
+	linked := make(map[string]bool)
+	for _, p := range i.Pages {
+		for _, target := range i.LinkedPages(p) {
+			linked[target.Url] = true
+		}
+	}
+
+	orphans := []*PageEntry{}
+	for _, p := range i.Pages {
+		if _, ordered := i.WeightLookup[p.Path]; !ordered && !linked[p.Url] {
+			orphans = append(orphans, p)
+		}
+	}
+
+	return orphans
+}
+
+// WriteDot writes the graph in the Graphviz DOT language, with the pages of
+// each section grouped together.
+func (g Graph) WriteDot(w io.Writer) error {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Title))
+	b.WriteString("  node [shape=box];\n")
+
+	sections := make(map[string][]GraphNode)
+	for _, n := range g.Nodes {
+		sections[n.Section] = append(sections[n.Section], n)
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		indent := "  "
+		if name != "" {
+			fmt.Fprintf(&b, "  subgraph %s {\n    label=%s;\n", dotQuote("cluster_"+name), dotQuote(name))
+			indent = "    "
+		}
+		for _, n := range sections[name] {
+			fmt.Fprintf(&b, "%s%s [label=%s];\n", indent, dotQuote(n.Id), dotQuote(n.Label))
+		}
+		if name != "" {
+			b.WriteString("  }\n")
+		}
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e.Source), dotQuote(e.Target))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type GraphSuite struct {
+	suite.Suite
+}
+
+func TestGraphSuite(t *testing.T) {
+	suite.Run(t, new(GraphSuite))
+}
+
+func (s *GraphSuite) index() *PageIndex {
+	i := newPageIndex()
+	pages := []*PageEntry{
+		{Url: "/intro", Path: "intro.md", Label: "Intro", Links: []Link{{Target: "/ops/run"}}},
+		{Url: "/ops/run", Path: "ops/run.md", Label: "Run \"book\"", Tags: []string{"ops"}, Links: []Link{{Target: "/intro"}, {Target: "/ops/run"}}},
+		{Url: "/ops/lost", Path: "ops/lost.md", Label: "Lost"},
+		{Url: "/listed", Path: "listed.md", Label: "Listed"},
+	}
+	for _, p := range pages {
+		i.PageLookup[p.Url] = p
+		i.Pages = append(i.Pages, p)
+	}
+	i.WeightLookup["listed.md"] = 1
+
+	return i
+}
+
+func (s *GraphSuite) TestGraph() {
+	g := s.index().Graph("Test")
+
+	s.Equal("Test", g.Title)
+	s.Len(g.Nodes, 4)
+	s.Equal(GraphNode{Id: "/ops/run", Label: "Run \"book\"", Path: "ops/run.md", Section: "ops", Tags: []string{"ops"}, Inbound: 1, Outbound: 1}, g.Nodes[1])
+	s.Equal([]string{}, g.Nodes[0].Tags)
+	s.Equal([]GraphEdge{{Source: "/intro", Target: "/ops/run"}, {Source: "/ops/run", Target: "/intro"}}, g.Edges)
+}
+
+func (s *GraphSuite) TestOrphans() {
+	orphans := s.index().Orphans()
+
+	s.Require().Len(orphans, 1)
+	s.Equal("/ops/lost", orphans[0].Url)
+}
+
+func (s *GraphSuite) TestWriteDot() {
+	out := bytes.Buffer{}
+	s.Require().NoError(s.index().Graph("Test").WriteDot(&out))
+
+	s.Equal(`digraph "Test" {
+  node [shape=box];
+  "/intro" [label="Intro"];
+  "/listed" [label="Listed"];
+  subgraph "cluster_ops" {
+    label="ops";
+    "/ops/run" [label="Run \"book\""];
+    "/ops/lost" [label="Lost"];
+  }
+  "/intro" -> "/ops/run";
+  "/ops/run" -> "/intro";
+}
+`, out.String())
+}
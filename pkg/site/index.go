@@ -25,51 +25,142 @@ import (
 	"path/filepath"
 	"sort"
 	"sync"
+	"time"
 )
 
 type PageIndex struct {
 	PageLookup    map[string]*PageEntry
+	PathLookup    map[string]*PageEntry
+	WikiLookup    map[string][]*PageEntry
+	Backlinks     map[string][]*PageEntry
+	Taxonomies    map[string]*Taxonomy
 	Pages         []*PageEntry
 	WeightLookup  map[string]float64
 	DefaultWeight float64
 	Title         string
+	Built         time.Time
+	BuildDuration time.Duration
 }
 
-var indexInit sync.Once
-var globalIndex *PageIndex
+type IndexStatus struct {
+	Indexed       bool          `json:"indexed"`
+	Pages         int           `json:"pages"`
+	Built         time.Time     `json:"built"`
+	BuildDuration time.Duration `json:"buildDurationNs"`
 
-func Index() *PageIndex {
-	indexInit.Do(func() {
-		ReIndex()
+	// The error from the last build attempt, if it failed
+	Error string `json:"error,omitempty"`
+}
+
+// Indexer owns the current index of a single site.
+type Indexer struct {
+	conf *config.Values
+
+	// Scan is called with each newly built index, before it is served
+	Scan func(i *PageIndex)
+
+	init    sync.Once
+	lock    sync.RWMutex
+	index   *PageIndex
+	lastErr error
+	history GitHistory
+}
+
+func NewIndexer(v *config.Values) *Indexer {
+	return &Indexer{conf: v}
+}
+
+func (x *Indexer) Index() *PageIndex {
+	x.init.Do(func() {
+		// Skip the build if the index was already built explicitly
+		if !x.Status().Indexed {
+			x.ReIndex()
+		}
 	})
 
-	return globalIndex
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	if x.index == nil {
+		// The first build failed, so there is nothing to serve yet
+		return newPageIndex()
+	}
+
+	return x.index
+}
+
+// Status reports on the current index without triggering a build.
+func (x *Indexer) Status() IndexStatus {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	status := IndexStatus{}
+	if x.lastErr != nil {
+		status.Error = x.lastErr.Error()
+	}
+
+	if x.index != nil {
+		status.Indexed = true
+		status.Pages = len(x.index.Pages)
+		status.Built = x.index.Built
+		status.BuildDuration = x.index.BuildDuration
+	}
+
+	return status
 }
 
-func ReIndex() *PageIndex {
-	var err error
-	globalIndex, err = BuildIndex()
+// ReIndex rebuilds the index. If the build fails, the previous index is kept
+// and returned along with the error.
+func (x *Indexer) ReIndex() (*PageIndex, error) {
+	i, err := buildIndex(x.conf, &x.history)
+	if err == nil {
+		if x.Scan != nil {
+			x.Scan(i)
+		}
+		i.linkBacklinks()
+	}
 
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	x.lastErr = err
 	if err != nil {
-		panic("failed to build site index")
+		log.Errorf("Failed to build index for %s: %s", x.conf.SitePath, err)
+		return x.index, err
 	}
 
-	return globalIndex
+	x.index = i
+
+	return i, nil
 }
 
-func BuildIndex() (*PageIndex, error) {
-	i := PageIndex{
+func newPageIndex() *PageIndex {
+	return &PageIndex{
 		PageLookup:    make(map[string]*PageEntry),
+		PathLookup:    make(map[string]*PageEntry),
+		WikiLookup:    make(map[string][]*PageEntry),
+		Backlinks:     make(map[string][]*PageEntry),
+		Taxonomies:    make(map[string]*Taxonomy),
 		Pages:         []*PageEntry{},
 		WeightLookup:  make(map[string]float64),
 		DefaultWeight: DefaultWeight,
 		Title:         "Contents",
 	}
+}
+
+func BuildIndex(v *config.Values) (*PageIndex, error) {
+	return buildIndex(v, &GitHistory{})
+}
+
+func buildIndex(v *config.Values, history *GitHistory) (*PageIndex, error) {
+	start := time.Now()
+
+	i := newPageIndex()
 
 	// Read order data
-	i.readOrder()
+	i.readOrder(v.ConfigPath)
 
-	err := filepath.Walk(config.Global().SitePath,
+	err := filepath.Walk(v.SitePath,
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -80,9 +171,9 @@ func BuildIndex() (*PageIndex, error) {
 				return nil
 			}
 
-			relPath, _ := filepath.Rel(config.Global().SitePath, path)
+			relPath, _ := filepath.Rel(v.SitePath, path)
 			log.Infof("Looking at file: %s", relPath)
-			i.addResource(relPath)
+			i.addResource(v, relPath)
 
 			return nil
 		})
@@ -91,8 +182,14 @@ func BuildIndex() (*PageIndex, error) {
 	}
 
 	i.calculateOrder()
+	if v.SiteConfig.Git.Enabled {
+		i.readGitHistory(v.SitePath, history)
+	}
+	i.buildTaxonomies(v)
+	i.Built = time.Now()
+	i.BuildDuration = i.Built.Sub(start)
 
-	return &i, nil
+	return i, nil
 }
 
 type OrderInfo struct {
@@ -101,32 +198,53 @@ type OrderInfo struct {
 	Order         []string `yaml:"order"`
 }
 
-func (i *PageIndex) readOrder() {
+const OrderFile = "order.yml"
+
+// ReadOrder loads the page order for a site. A site without an order file
+// has no order, which is not an error. Strict loading rejects unknown keys.
+func ReadOrder(configPath string, strict bool) (*OrderInfo, error) {
 	var order = OrderInfo{
 		DefaultWeight: DefaultWeight,
 		OrderOrigin:   1,
 		Order:         []string{},
 	}
 
-	// Zero the order
-	i.WeightLookup = make(map[string]float64)
-	i.DefaultWeight = order.DefaultWeight
+	orderData, err := ioutil.ReadFile(filepath.Join(configPath, OrderFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	unmarshal := yaml.Unmarshal
+	if strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
 
-	// Read the file
-	orderFile := filepath.Join(config.Global().ConfigPath, "order.yml")
-	orderData, err := ioutil.ReadFile(orderFile)
+	err = unmarshal(orderData, &order)
 	if err != nil {
-		// Do nothing.
-		return
+		return nil, err
 	}
-	log.Infof("Read file order from: %s", orderFile)
 
-	err = yaml.Unmarshal(orderData, &order)
+	return &order, nil
+}
+
+func (i *PageIndex) readOrder(configPath string) {
+	// Zero the order
+	i.WeightLookup = make(map[string]float64)
+	i.DefaultWeight = DefaultWeight
+
+	order, err := ReadOrder(configPath, false)
 	if err != nil {
 		// Do nothing, but log the abnormal error
 		log.Errorf("Error while parsing order info: %s", err)
 		return
 	}
+	if order == nil {
+		return
+	}
+	log.Infof("Read file order from: %s", filepath.Join(configPath, OrderFile))
 
 	// Assign weights by index
 	for wd, u := range order.Order {
@@ -134,10 +252,11 @@ func (i *PageIndex) readOrder() {
 	}
 }
 
-func (i *PageIndex) addResource(path string) {
-	p, err := LoadPageEntry(path)
+func (i *PageIndex) addResource(v *config.Values, path string) {
+	p, err := LoadPageEntry(v, path)
 	if err != nil {
 		log.Errorf("Failed to load resource [%s]: %s", path, err)
+		return
 	}
 
 	w, ok := i.WeightLookup[p.Path]
@@ -148,6 +267,43 @@ func (i *PageIndex) addResource(path string) {
 	}
 
 	i.PageLookup[p.Url] = p
+	i.PathLookup[filepath.ToSlash(p.Path)] = p
+	i.addWikiNames(p)
+}
+
+// Filter creates a copy of the index holding only the pages accepted by the
+// filter function.
+func (i *PageIndex) Filter(accept func(p *PageEntry) bool) *PageIndex {
+	f := *i
+	f.PageLookup = make(map[string]*PageEntry)
+	f.PathLookup = make(map[string]*PageEntry)
+	f.WikiLookup = make(map[string][]*PageEntry)
+	f.Backlinks = make(map[string][]*PageEntry)
+	f.Pages = make([]*PageEntry, 0, len(i.Pages))
+
+	for _, p := range i.Pages {
+		if accept(p) {
+			f.Pages = append(f.Pages, p)
+			f.PageLookup[p.Url] = p
+			f.PathLookup[filepath.ToSlash(p.Path)] = p
+			f.addWikiNames(p)
+		}
+	}
+
+	for target, pages := range i.Backlinks {
+		for _, p := range pages {
+			if _, ok := f.PageLookup[p.Url]; ok {
+				f.Backlinks[target] = append(f.Backlinks[target], p)
+			}
+		}
+	}
+
+	f.Taxonomies = make(map[string]*Taxonomy)
+	for name, t := range i.Taxonomies {
+		f.Taxonomies[name] = t.filter(&f)
+	}
+
+	return &f
 }
 
 func (i *PageIndex) calculateOrder() {
@@ -28,6 +28,7 @@ import (
 
 type SiteSuite struct {
 	suite.Suite
+	values *config.Values
 }
 
 func TestSiteSuite(t *testing.T) {
@@ -39,6 +40,10 @@ func (s *SiteSuite) SetupTest() {
 }
 
 func (s *SiteSuite) loadSite(siteName string) {
+	s.values = loadTestValues(&s.Suite, siteName)
+}
+
+func loadTestValues(s *suite.Suite, siteName string) *config.Values {
 	v := config.Create()
 	cwd, cwdErr := os.Getwd()
 	s.Require().NoError(cwdErr)
@@ -47,15 +52,16 @@ func (s *SiteSuite) loadSite(siteName string) {
 	testdataPath := filepath.Join(basedir, "testdata/sites/"+siteName)
 	v.ConfigPath = filepath.Join(testdataPath, "config")
 	v.SitePath = filepath.Join(testdataPath, "site")
-	config.SetGlobal(v)
 
-	siteConf, siteErr := config.LoadSiteConfig()
+	siteConf, siteErr := config.LoadSiteConfig(v)
 	s.Require().NoError(siteErr)
-	config.Global().SiteConfig = siteConf
+	v.SiteConfig = siteConf
+
+	return v
 }
 
 func (s *SiteSuite) TestCreateIndex_Simple() {
-	i, err := BuildIndex()
+	i, err := BuildIndex(s.values)
 
 	s.Require().NoError(err)
 	s.NotNil(i)
@@ -74,7 +80,7 @@ func (s *SiteSuite) TestCreateIndex_Simple() {
 
 func (s *SiteSuite) TestCreateIndex_NoOrderYaml() {
 	s.loadSite("fail03")
-	i, err := BuildIndex()
+	i, err := BuildIndex(s.values)
 
 	s.Require().NoError(err)
 	s.NotNil(i)
@@ -84,8 +90,8 @@ func (s *SiteSuite) TestCreateIndex_NoOrderYaml() {
 
 func (s *SiteSuite) TestCreateIndex_BadSitePath() {
 	s.loadSite("fail03")
-	config.Global().SitePath = "/@@@@/BadPATH"
-	i, err := BuildIndex()
+	s.values.SitePath = "/@@@@/BadPATH"
+	i, err := BuildIndex(s.values)
 
 	s.Error(err)
 	s.Nil(i)
@@ -93,7 +99,7 @@ func (s *SiteSuite) TestCreateIndex_BadSitePath() {
 
 func (s *SiteSuite) TestCreateIndex_BadOrderFile() {
 	s.loadSite("fail04")
-	i, err := BuildIndex()
+	i, err := BuildIndex(s.values)
 
 	s.Require().NoError(err)
 	s.NotNil(i)
@@ -103,39 +109,75 @@ func (s *SiteSuite) TestCreateIndex_BadOrderFile() {
 
 func (s *SiteSuite) TestIndex_Fails() {
 	s.loadSite("fail03")
-	config.Global().SitePath = "/@@@@/BadPATH"
+	s.values.SitePath = "/@@@@/BadPATH"
+
+	x := NewIndexer(s.values)
+
+	i, err := x.ReIndex()
+	s.Error(err)
+	s.Nil(i)
+
+	st := x.Status()
+	s.False(st.Indexed)
+	s.NotEmpty(st.Error)
+	s.Empty(x.Index().Pages)
+}
 
-	s.Panics(func() {
-		i := ReIndex()
-		s.Nil(i)
-	})
+func (s *SiteSuite) TestReIndex_KeepsPrevious() {
+	x := NewIndexer(s.values)
+	i := x.Index()
+
+	s.values.SitePath = "/@@@@/BadPATH"
+	i2, err := x.ReIndex()
+	s.Error(err)
+	s.Equal(reflect.ValueOf(i).Pointer(), reflect.ValueOf(i2).Pointer())
+	s.Equal(reflect.ValueOf(i).Pointer(), reflect.ValueOf(x.Index()).Pointer())
+
+	st := x.Status()
+	s.True(st.Indexed)
+	s.Equal(4, st.Pages)
+	s.NotEmpty(st.Error)
 }
 
 func (s *SiteSuite) TestReIndex() {
-	i := ReIndex()
+	x := NewIndexer(s.values)
+	i, err := x.ReIndex()
 
+	s.NoError(err)
 	s.NotNil(i)
 
-	i2 := ReIndex()
+	i2, _ := x.ReIndex()
 	s.NotEqual(reflect.ValueOf(i).Pointer(), reflect.ValueOf(i2).Pointer())
 }
 
 func (s *SiteSuite) TestIndex_Singleton() {
-	i := Index()
+	x := NewIndexer(s.values)
+	i := x.Index()
 
 	s.NotNil(i)
 
-	i2 := Index()
+	i2 := x.Index()
 	s.Equal(reflect.ValueOf(i).Pointer(), reflect.ValueOf(i2).Pointer())
 }
 
 func (s *SiteSuite) TestIndex_PostReIndex() {
-	i0 := Index()
-	i := ReIndex()
+	x := NewIndexer(s.values)
+	i0 := x.Index()
+	i, _ := x.ReIndex()
 
 	s.NotNil(i)
 
-	i2 := Index()
+	i2 := x.Index()
 	s.NotEqual(reflect.ValueOf(i).Pointer(), reflect.ValueOf(i0).Pointer())
 	s.Equal(reflect.ValueOf(i).Pointer(), reflect.ValueOf(i2).Pointer())
 }
+
+func (s *SiteSuite) TestIndex_Independent() {
+	x1 := NewIndexer(s.values)
+
+	s.loadSite("fail03")
+	x2 := NewIndexer(s.values)
+
+	s.Len(x1.Index().Pages, 4)
+	s.Len(x2.Index().Pages, 1)
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"github.com/zpxio/mdsite/pkg/config"
+	"golang.org/x/net/html"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Link kinds
+const (
+	LinkAnchor = "link"
+	LinkImage  = "image"
+)
+
+// BrokenLinkClass marks broken links in pages rendered in development mode.
+const BrokenLinkClass = "mdsite-broken-link"
+
+type Link struct {
+	Href     string `json:"href"`
+	Kind     string `json:"kind"`
+	Target   string `json:"target,omitempty"`
+	External bool   `json:"external,omitempty"`
+	Broken   bool   `json:"broken,omitempty"`
+}
+
+// linkAttr finds the attribute holding the link target of an element.
+func linkAttr(t html.Token) (string, string, bool) {
+	var kind, attr string
+
+	switch t.Data {
+	case "a":
+		kind, attr = LinkAnchor, "href"
+	case "img":
+		kind, attr = LinkImage, "src"
+	default:
+		return "", "", false
+	}
+
+	for _, a := range t.Attr {
+		if a.Namespace == "" && a.Key == attr {
+			return kind, a.Val, true
+		}
+	}
+
+	return "", "", false
+}
+
+// ExtractLinks lists the links and images in an HTML document or fragment.
+func ExtractLinks(r io.Reader) []Link {
+	var links []Link
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if kind, href, ok := linkAttr(z.Token()); ok {
+				links = append(links, Link{Href: href, Kind: kind})
+			}
+		}
+	}
+}
+
+// ResolveLink resolves a link on a page to a site URL path. Links with a
+// scheme or host are external, and are not resolved.
+func ResolveLink(pageUrl string, href string) (string, bool) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	if ref.Scheme != "" || ref.Host != "" {
+		return "", false
+	}
+
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return "", false
+	}
+
+	target := base.ResolveReference(ref).Path
+	if target == "" {
+		return pageUrl, true
+	}
+
+	return path.Clean(target), true
+}
+
+// StripBasePath strips the base path prefix from a request path. Paths
+// outside of the prefix are returned as an empty string.
+func StripBasePath(base string, requestPath string) string {
+	// Resolve any dot segments, so the path checked is the path served
+	requestPath = path.Clean("/" + requestPath)
+
+	if base == "" {
+		return requestPath
+	}
+
+	if requestPath != base && !strings.HasPrefix(requestPath, base+"/") {
+		return ""
+	}
+
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(requestPath, base), "/")
+}
+
+// SourceLinks creates a link rewriter for a page rendered from a source file,
+// given as a slash separated path within the site. Relative links are
+// resolved against the location of the file, and links to the source file of
+// an accepted page lead to its URL instead. External links are left alone.
+func (i *PageIndex) SourceLinks(v *config.Values, source string, accept func(p *PageEntry) bool) func(href string) string {
+	dir := path.Dir(source)
+
+	return func(href string) string {
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Path == "" {
+			return href
+		}
+
+		absolute := path.IsAbs(ref.Path)
+		target := path.Clean(ref.Path)
+		if !absolute {
+			target = path.Join(dir, ref.Path)
+			if target == ".." || strings.HasPrefix(target, "../") {
+				// Outside of the site
+				return href
+			}
+		}
+
+		if p, ok := i.PathLookup[strings.TrimPrefix(target, "/")]; ok && accept(p) {
+			ref.Path = p.Url
+		} else if absolute {
+			return href
+		} else {
+			ref.Path = v.SiteUrl(target)
+		}
+		ref.RawPath = ""
+
+		return ref.String()
+	}
+}
+
+// RewriteLinks replaces the targets of the links and images in an HTML
+// document or fragment. Everything else is copied unchanged.
+func RewriteLinks(content []byte, rewrite func(href string) string) []byte {
+	out := bytes.Buffer{}
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return out.Bytes()
+		}
+
+		raw := z.Raw()
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+
+		t := z.Token()
+		_, href, ok := linkAttr(t)
+		if !ok {
+			out.Write(raw)
+			continue
+		}
+
+		target := rewrite(href)
+		if target == href {
+			out.Write(raw)
+			continue
+		}
+
+		key := "src"
+		if t.Data == "a" {
+			key = "href"
+		}
+		for n, a := range t.Attr {
+			if a.Namespace == "" && a.Key == key {
+				t.Attr[n].Val = target
+				break
+			}
+		}
+		out.WriteString(t.String())
+	}
+}
+
+// CheckLinks resolves the links found on a page, and marks the internal
+// links that do not lead anywhere.
+func (i *PageIndex) CheckLinks(p *PageEntry, links []Link, exists func(target string) bool) []Link {
+	for n := range links {
+		l := &links[n]
+
+		target, internal := ResolveLink(p.Url, l.Href)
+		if !internal {
+			l.External = true
+			continue
+		}
+
+		l.Target = target
+		if _, ok := i.PageLookup[target]; !ok && !exists(target) {
+			l.Broken = true
+		}
+	}
+
+	return links
+}
+
+// BrokenLinks lists the broken links on a page.
+func (p *PageEntry) BrokenLinks() []Link {
+	var broken []Link
+	for _, l := range p.Links {
+		if l.Broken {
+			broken = append(broken, l)
+		}
+	}
+
+	return broken
+}
+
+// MarkBrokenLinks adds a visible marker to the broken links in an HTML
+// fragment.
+func MarkBrokenLinks(content []byte, broken []Link) []byte {
+	if len(broken) == 0 {
+		return content
+	}
+
+	hrefs := make(map[string]bool)
+	for _, l := range broken {
+		hrefs[l.Href] = true
+	}
+
+	out := bytes.Buffer{}
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return out.Bytes()
+		}
+
+		raw := z.Raw()
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+
+		t := z.Token()
+		_, href, ok := linkAttr(t)
+		if !ok || !hrefs[href] {
+			out.Write(raw)
+			continue
+		}
+
+		class := BrokenLinkClass
+		attrs := make([]html.Attribute, 0, len(t.Attr)+3)
+		for _, a := range t.Attr {
+			switch a.Key {
+			case "class":
+				class = a.Val + " " + class
+			case "title", "style":
+			default:
+				attrs = append(attrs, a)
+			}
+		}
+
+		t.Attr = append(attrs,
+			html.Attribute{Key: "class", Val: class},
+			html.Attribute{Key: "title", Val: "Broken link: " + href},
+			html.Attribute{Key: "style", Val: "color: #c00; text-decoration: line-through wavy"},
+		)
+		out.WriteString(t.String())
+	}
+}
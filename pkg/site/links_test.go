@@ -0,0 +1,196 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type LinksSuite struct {
+	suite.Suite
+}
+
+func TestLinksSuite(t *testing.T) {
+	suite.Run(t, new(LinksSuite))
+}
+
+func (s *LinksSuite) TestExtractLinks() {
+	links := ExtractLinks(strings.NewReader(`<p><a href="one">1</a> <a name="x">no href</a><img src="pic.png"/><br/><a href="https://example.com">ext</a></p>`))
+
+	s.Equal([]Link{
+		{Href: "one", Kind: LinkAnchor},
+		{Href: "pic.png", Kind: LinkImage},
+		{Href: "https://example.com", Kind: LinkAnchor},
+	}, links)
+}
+
+func (s *LinksSuite) TestResolveLink() {
+	cases := []struct {
+		href     string
+		target   string
+		internal bool
+	}{
+		{"other", "/docs/other", true},
+		{"../top", "/top", true},
+		{"/abs/page", "/abs/page", true},
+		{"sub/page?x=1#frag", "/docs/sub/page", true},
+		{"#frag", "/docs/page", true},
+		{"dir/", "/docs/dir", true},
+		{"https://example.com/x", "", false},
+		{"//example.com/x", "", false},
+		{"mailto:someone@example.com", "", false},
+	}
+
+	for _, c := range cases {
+		target, internal := ResolveLink("/docs/page", c.href)
+		s.Equal(c.internal, internal, c.href)
+		s.Equal(c.target, target, c.href)
+	}
+}
+
+func (s *LinksSuite) TestCheckLinks() {
+	i := newPageIndex()
+	page := &PageEntry{Url: "/docs/page"}
+	i.PageLookup["/docs/other"] = &PageEntry{Url: "/docs/other"}
+
+	links := i.CheckLinks(page, []Link{
+		{Href: "other", Kind: LinkAnchor},
+		{Href: "gone", Kind: LinkAnchor},
+		{Href: "known.png", Kind: LinkImage},
+		{Href: "https://example.com", Kind: LinkAnchor},
+	}, func(target string) bool {
+		return target == "/docs/known.png"
+	})
+
+	s.False(links[0].Broken)
+	s.Equal("/docs/other", links[0].Target)
+	s.True(links[1].Broken)
+	s.False(links[2].Broken)
+	s.True(links[3].External)
+	s.False(links[3].Broken)
+
+	page.Links = links
+	s.Equal([]Link{links[1]}, page.BrokenLinks())
+}
+
+func (s *LinksSuite) TestStripBasePath() {
+	s.Equal("/page", StripBasePath("", "/page"))
+	s.Equal("/page", StripBasePath("/base", "/base/page"))
+	s.Equal("/", StripBasePath("/base", "/base"))
+	s.Equal("", StripBasePath("/base", "/other/page"))
+	s.Equal("", StripBasePath("/base", "/base/../other"))
+}
+
+func (s *LinksSuite) TestLinkExists() {
+	dir, err := ioutil.TempDir("", "links")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "logo.png"), []byte("png"), 0644))
+
+	v := config.Create()
+	v.SitePath = dir
+	v.BasePath = "/base"
+	v.SiteConfig.Taxonomies = []config.TaxonomyConfig{{Name: "topics", Path: "topics"}}
+
+	s.True(LinkExists(v, "/base/"))
+	s.True(LinkExists(v, "/base/toc"))
+	s.True(LinkExists(v, "/base"+LinkReportPath))
+	s.True(LinkExists(v, "/base/logo.png"))
+	s.True(LinkExists(v, "/base/api/graph"))
+	s.True(LinkExists(v, "/base/sitemap.xml"))
+	s.True(LinkExists(v, "/base/sitemap/2.xml"))
+	s.True(LinkExists(v, "/base/robots.txt"))
+	s.True(LinkExists(v, "/base/feed.atom"))
+	s.True(LinkExists(v, "/base/feed.rss"))
+	s.True(LinkExists(v, "/base/feed.json"))
+	s.True(LinkExists(v, "/base/feeds/sections/ops.atom"))
+	s.True(LinkExists(v, "/base/topics"))
+	s.True(LinkExists(v, "/base/topics/billing"))
+	s.False(LinkExists(v, "/base/tags/billing"))
+	s.False(LinkExists(v, "/base/feed.xml"))
+	s.False(LinkExists(v, "/base/tocs"))
+	s.True(LinkExists(v, "/elsewhere"))
+	s.False(LinkExists(v, "/base/missing.png"))
+	s.False(LinkExists(v, "/base/missing"))
+}
+
+func (s *LinksSuite) TestMarkBrokenLinks() {
+	content := `<p><a href="ok">fine</a> <a class="x" href="gone">broken</a> <img src="gone.png"></p>`
+
+	marked := string(MarkBrokenLinks([]byte(content), []Link{
+		{Href: "gone", Kind: LinkAnchor, Broken: true},
+		{Href: "gone.png", Kind: LinkImage, Broken: true},
+	}))
+
+	s.Contains(marked, `<a href="ok">fine</a>`)
+	s.Contains(marked, `<a href="gone" class="x mdsite-broken-link" title="Broken link: gone"`)
+	s.Contains(marked, `<img src="gone.png" class="mdsite-broken-link"`)
+	s.Equal(content, string(MarkBrokenLinks([]byte(content), nil)))
+}
+
+func (s *LinksSuite) TestSourceLinks() {
+	v := config.Create()
+	v.BasePath = "/base"
+
+	i := newPageIndex()
+	i.PathLookup["dev/setup.md"] = &PageEntry{Path: "dev/setup.md", Url: "/base/dev/setup"}
+	i.PathLookup["guide.md"] = &PageEntry{Path: "guide.md", Url: "/base/guide"}
+	i.PathLookup["notes.txt"] = &PageEntry{Path: "notes.txt", Url: "/base/notes"}
+
+	rewrite := i.SourceLinks(v, "user/intro.md", func(p *PageEntry) bool {
+		return p.Path != "notes.txt"
+	})
+
+	cases := map[string]string{
+		"../dev/setup.md":          "/base/dev/setup",
+		"../dev/setup.md#install":  "/base/dev/setup#install",
+		"../guide.md?view=1#top":   "/base/guide?view=1#top",
+		"/guide.md":                "/base/guide",
+		"/unknown.md":              "/unknown.md",
+		"images/shot.png":          "/base/user/images/shot.png",
+		"../notes.txt":             "/base/notes.txt",
+		"other":                    "/base/user/other",
+		"../../outside.md":         "../../outside.md",
+		"#anchor":                  "#anchor",
+		"?q=1":                     "?q=1",
+		"https://example.com/x.md": "https://example.com/x.md",
+		"mailto:someone@example":   "mailto:someone@example",
+	}
+
+	for href, expected := range cases {
+		s.Equal(expected, rewrite(href), href)
+	}
+}
+
+func (s *LinksSuite) TestRewriteLinks() {
+	content := `<p><a class="x" href="a.md">A</a> <a name="n">N</a> <img src="b.png" alt="B"/> <span data-href="a.md">a.md</span></p>`
+
+	rewritten := string(RewriteLinks([]byte(content), func(href string) string {
+		if href == "a.md" {
+			return "/a"
+		}
+		return href
+	}))
+
+	s.Equal(`<p><a class="x" href="/a">A</a> <a name="n">N</a> <img src="b.png" alt="B"/> <span data-href="a.md">a.md</span></p>`, rewritten)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/zpxio/mdsite/pkg/config"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Crumb is one step of the section chain leading to a page. The URL is empty
+// for sections without an index page.
+type Crumb struct {
+	Label string
+	Url   string
+	Page  *PageEntry
+}
+
+// IndexPage finds the index page of a section, given as a slash separated
+// directory within the site. The index of a section is either an "index"
+// page inside of the directory, or a page named after the directory.
+func (i *PageIndex) IndexPage(v *config.Values, dir string) *PageEntry {
+	routes := []string{path.Join("/", dir, "index")}
+	if dir != "" {
+		routes = append(routes, "/"+dir)
+	}
+
+	for _, route := range routes {
+		if p, ok := i.PageLookup[v.SiteUrl(route)]; ok {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// SectionCrumb describes a section, given as a slash separated directory
+// within the site. It only has a link if the section has an index page.
+func (i *PageIndex) SectionCrumb(v *config.Values, section string) Crumb {
+	c := Crumb{Label: generateLabel(path.Base(section))}
+	if index := i.IndexPage(v, section); index != nil {
+		c.Url, c.Page = index.Url, index
+	}
+
+	return c
+}
+
+// Breadcrumbs lists the sections leading to a page, starting from the top of
+// the site. A page is never a crumb on its own chain, so section index pages
+// end with their parent section.
+func (i *PageIndex) Breadcrumbs(v *config.Values, p *PageEntry) []Crumb {
+	root := Crumb{Label: v.SiteConfig.Title, Url: v.SiteUrl("/")}
+	if index := i.IndexPage(v, ""); index != nil {
+		root.Url, root.Page = index.Url, index
+	}
+	crumbs := []Crumb{root}
+
+	dir := path.Dir(filepath.ToSlash(p.Path))
+	if dir == "." {
+		dir = ""
+	}
+
+	var parts []string
+	if dir != "" {
+		parts = strings.Split(dir, "/")
+	}
+
+	for n := range parts {
+		crumbs = append(crumbs, i.SectionCrumb(v, strings.Join(parts[:n+1], "/")))
+	}
+
+	// Section index pages lead to themselves
+	for n, c := range crumbs {
+		if c.Page == p {
+			return crumbs[:n]
+		}
+	}
+
+	return crumbs
+}
+
+// Neighbours finds the pages before and after a page in the page order.
+func (i *PageIndex) Neighbours(p *PageEntry) (*PageEntry, *PageEntry) {
+	for n, page := range i.Pages {
+		if page != p {
+			continue
+		}
+
+		var prev, next *PageEntry
+		if n > 0 {
+			prev = i.Pages[n-1]
+		}
+		if n < len(i.Pages)-1 {
+			next = i.Pages[n+1]
+		}
+		return prev, next
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type NavigationSuite struct {
+	suite.Suite
+}
+
+func TestNavigationSuite(t *testing.T) {
+	suite.Run(t, new(NavigationSuite))
+}
+
+func (s *NavigationSuite) build() *PageIndex {
+	i, err := BuildIndex(loadTestValues(&s.Suite, "nav01"))
+	s.Require().NoError(err)
+
+	return i
+}
+
+func (s *NavigationSuite) TestBreadcrumbs() {
+	v := loadTestValues(&s.Suite, "nav01")
+	i := s.build()
+
+	crumbs := i.Breadcrumbs(v, i.PageLookup["/ops/db/failover"])
+	s.Require().Len(crumbs, 3)
+	s.Equal(Crumb{Label: "Nav01", Url: "/index", Page: i.PageLookup["/index"]}, crumbs[0])
+	s.Equal(Crumb{Label: "Ops", Url: "/ops", Page: i.PageLookup["/ops"]}, crumbs[1])
+	s.Equal(Crumb{Label: "Db"}, crumbs[2])
+
+	// Section index pages end with their parent
+	crumbs = i.Breadcrumbs(v, i.PageLookup["/guides/index"])
+	s.Require().Len(crumbs, 1)
+	s.Equal("Nav01", crumbs[0].Label)
+
+	s.Empty(i.Breadcrumbs(v, i.PageLookup["/index"]))
+}
+
+func (s *NavigationSuite) TestBreadcrumbs_NoRootIndex() {
+	v := loadTestValues(&s.Suite, "nav01")
+	v.BasePath = "/docs"
+	i := newPageIndex()
+	p := &PageEntry{Path: "ops/restart.md", Url: "/docs/ops/restart"}
+	i.PageLookup[p.Url] = p
+
+	s.Equal([]Crumb{{Label: "Nav01", Url: "/docs/"}, {Label: "Ops"}}, i.Breadcrumbs(v, p))
+}
+
+func (s *NavigationSuite) TestNeighbours() {
+	i := s.build()
+
+	prev, next := i.Neighbours(i.PageLookup["/ops/restart"])
+	s.Equal("/ops", prev.Url)
+	s.Equal("/ops/db/failover", next.Url)
+
+	prev, next = i.Neighbours(i.PageLookup["/index"])
+	s.Nil(prev)
+	s.Equal("/ops", next.Url)
+
+	prev, next = i.Neighbours(&PageEntry{Url: "/unknown"})
+	s.Nil(prev)
+	s.Nil(next)
+}
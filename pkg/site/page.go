@@ -19,10 +19,14 @@ package site
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"github.com/apex/log"
 	"github.com/zpxio/mdsite/pkg/config"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unicode"
 )
@@ -34,18 +38,31 @@ type PageEntry struct {
 	Path       string
 	Extension  string
 	Url        string
+	Route      string
 	Label      string
 	ListWeight float64
 	Modified   time.Time
+	// The date and summary given in the front matter, if any
+	Date    time.Time
+	Summary string
+	Tags    []string
+	// How the page is listed in the sitemap
+	Sitemap SitemapOptions
+	// The last commit to change the page, if the site is in a git repository
+	Commit *GitCommit
+	// Terms for each taxonomy of the site, from the front matter
+	Terms map[string][]string
+
+	// Links found on the page when it was indexed
+	Links []Link
+	// Wiki link names on the page that match more than one page
+	AmbiguousLinks []string
 }
 
-var nextId uint64 = 1
+var lastId uint64 = 0
 
 func nextPageId() uint64 {
-	i := nextId
-	nextId++
-
-	return i
+	return atomic.AddUint64(&lastId, 1)
 }
 
 const (
@@ -91,9 +108,9 @@ func generateLabel(path string) string {
 	return buf.String()
 }
 
-func LoadPageEntry(path string) (*PageEntry, error) {
+func LoadPageEntry(v *config.Values, path string) (*PageEntry, error) {
 	id := nextPageId()
-	fullPath := filepath.Join(config.Global().SitePath, path)
+	fullPath := filepath.Join(v.SitePath, path)
 	fs, statErr := os.Stat(fullPath)
 	if statErr != nil {
 		return nil, statErr
@@ -106,7 +123,8 @@ func LoadPageEntry(path string) (*PageEntry, error) {
 
 	// Generate a Url from the file path
 	ext := filepath.Ext(path)
-	url := "/" + strings.TrimSuffix(path, ext)
+	route := "/" + filepath.ToSlash(strings.TrimSuffix(path, ext))
+	url := v.SiteUrl(route)
 	// Fix the extension
 	ext = strings.TrimPrefix(ext, ".")
 
@@ -115,10 +133,84 @@ func LoadPageEntry(path string) (*PageEntry, error) {
 		Path:       path,
 		Extension:  ext,
 		Url:        url,
+		Route:      route,
 		Label:      generateLabel(path),
 		Modified:   fs.ModTime(),
 		ListWeight: DefaultWeight,
 	}
 
+	if ext == "md" {
+		pe.readFrontMatter(fullPath, v.SiteConfig.Taxonomies)
+	}
+
 	return &pe, nil
 }
+
+// readFrontMatter fills in the details given in the front matter of a page.
+// Broken front matter is logged, but the page is still indexed.
+func (p *PageEntry) readFrontMatter(fullPath string, taxonomies []config.TaxonomyConfig) {
+	data, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		log.Warnf("Could not read front matter of [%s]: %s", p.Path, err)
+		return
+	}
+
+	fm, err := ParseFrontMatter(data)
+	if err != nil {
+		log.Warnf("Invalid front matter in [%s]: %s", p.Path, err)
+		return
+	}
+
+	p.Tags = fm.Tags
+	p.Date = fm.Date.Time
+	p.Summary = strings.TrimSpace(fm.Summary)
+	p.Sitemap = fm.Sitemap
+
+	if p.Sitemap.Priority < 0 || p.Sitemap.Priority > 1 {
+		log.Warnf("Ignoring sitemap priority of [%s], which must be from 0 to 1: %g", p.Path, p.Sitemap.Priority)
+		p.Sitemap.Priority = 0
+	}
+
+	for _, t := range taxonomies {
+		if terms := fm.Terms(t.Name); len(terms) > 0 {
+			if p.Terms == nil {
+				p.Terms = make(map[string][]string)
+			}
+			p.Terms[t.Name] = terms
+		}
+	}
+}
+
+// Updated is the date of the page from its front matter, or when the file
+// was last modified.
+func (p *PageEntry) Updated() time.Time {
+	if !p.Date.IsZero() {
+		return p.Date
+	}
+
+	return p.Modified
+}
+
+// LastUpdated describes when the page last changed, and who changed it if
+// that is known from git.
+func (p *PageEntry) LastUpdated() string {
+	date := p.Modified.Format("2 January 2006")
+	if p.Commit != nil && p.Commit.Author != "" {
+		return fmt.Sprintf("Last updated by %s on %s", p.Commit.Author, date)
+	}
+
+	return "Last updated on " + date
+}
+
+// Section is the top level directory of the page, or an empty string for
+// pages at the top of the site.
+func (p *PageEntry) Section() string {
+	slashPath := filepath.ToSlash(p.Path)
+
+	n := strings.Index(slashPath, "/")
+	if n < 0 {
+		return ""
+	}
+
+	return slashPath[:n]
+}
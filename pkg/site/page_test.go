@@ -26,6 +26,7 @@ import (
 
 type PageSuite struct {
 	suite.Suite
+	values *config.Values
 }
 
 func TestPageSuite(t *testing.T) {
@@ -41,11 +42,12 @@ func (s *PageSuite) SetupTest() {
 	testdataPath := filepath.Join(basedir, "testdata/sites/test01")
 	v.ConfigPath = filepath.Join(testdataPath, "config")
 	v.SitePath = filepath.Join(testdataPath, "site")
-	config.SetGlobal(v)
 
-	siteConf, siteErr := config.LoadSiteConfig()
+	siteConf, siteErr := config.LoadSiteConfig(v)
 	s.Require().NoError(siteErr)
-	config.Global().SiteConfig = siteConf
+	v.SiteConfig = siteConf
+
+	s.values = v
 }
 
 func (s *PageSuite) TestGenerateLabel_Simple() {
@@ -82,10 +84,10 @@ func (s *PageSuite) TestGenerateLabel_MultiCapCamelCase() {
 
 func (s *PageSuite) TestLoadPageEntry_Happy() {
 	filePath := "sample-01.md"
-	fileStats, fsErr := os.Stat(filepath.Join(config.Global().SitePath, filePath))
+	fileStats, fsErr := os.Stat(filepath.Join(s.values.SitePath, filePath))
 	s.Require().NoError(fsErr)
 
-	p, err := LoadPageEntry(filePath)
+	p, err := LoadPageEntry(s.values, filePath)
 
 	s.Require().NoError(err)
 	s.Equal(filePath, p.Path)
@@ -98,10 +100,10 @@ func (s *PageSuite) TestLoadPageEntry_Happy() {
 
 func (s *PageSuite) TestLoadPageEntry_SubDirFile() {
 	filePath := "info/deep-file.txt"
-	fileStats, fsErr := os.Stat(filepath.Join(config.Global().SitePath, filePath))
+	fileStats, fsErr := os.Stat(filepath.Join(s.values.SitePath, filePath))
 	s.Require().NoError(fsErr)
 
-	p, err := LoadPageEntry(filePath)
+	p, err := LoadPageEntry(s.values, filePath)
 
 	s.Require().NoError(err)
 	s.Equal(filePath, p.Path)
@@ -112,9 +114,18 @@ func (s *PageSuite) TestLoadPageEntry_SubDirFile() {
 	s.Greater(p.Id, uint64(0))
 }
 
+func (s *PageSuite) TestLoadPageEntry_BasePath() {
+	s.values.BasePath = "/docs"
+
+	p, err := LoadPageEntry(s.values, "info/deep-file.txt")
+
+	s.Require().NoError(err)
+	s.Equal("/docs/info/deep-file", p.Url)
+}
+
 func (s *PageSuite) TestLoadPageEntry_Missing() {
 	filePath := "missing-01.txt"
-	p, err := LoadPageEntry(filePath)
+	p, err := LoadPageEntry(s.values, filePath)
 
 	s.Error(err)
 	s.Nil(p)
@@ -122,7 +133,7 @@ func (s *PageSuite) TestLoadPageEntry_Missing() {
 
 func (s *PageSuite) TestLoadPageEntry_Dir() {
 	filePath := "info"
-	p, err := LoadPageEntry(filePath)
+	p, err := LoadPageEntry(s.values, filePath)
 
 	s.Error(err)
 	s.Nil(p)
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/zpxio/mdsite/pkg/config"
+	"github.com/zpxio/mdsite/pkg/util"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Paths served by every site besides its pages and files
+const (
+	ApiPath         = "/api"
+	FeedPath        = "/feeds"
+	LinkReportPath  = "/_mdsite/links"
+	RobotsPath      = "/robots.txt"
+	SitemapPath     = "/sitemap.xml"
+	SitemapPartPath = "/sitemap"
+)
+
+// Route is a path served by a site that is not a page or a file.
+type Route struct {
+	Path string
+	// Every path below Path is served as well
+	Prefix bool
+}
+
+func (r Route) Matches(route string) bool {
+	if route == r.Path {
+		return true
+	}
+
+	return r.Prefix && strings.HasPrefix(route, r.Path+"/")
+}
+
+// Routes lists the paths a site serves besides its pages and files, so that
+// links to them can be checked.
+func Routes(sc *config.Site) []Route {
+	routes := []Route{
+		{Path: "/"},
+		{Path: "/toc"},
+		{Path: LinkReportPath},
+		{Path: ApiPath, Prefix: true},
+		{Path: SitemapPath},
+		{Path: SitemapPartPath, Prefix: true},
+		{Path: RobotsPath},
+		{Path: FeedPath, Prefix: true},
+	}
+
+	for _, format := range FeedFormats {
+		routes = append(routes, Route{Path: "/feed." + format})
+	}
+
+	for _, tc := range sc.Taxonomies {
+		routes = append(routes, Route{Path: "/" + tc.Path, Prefix: true})
+	}
+
+	return routes
+}
+
+// LinkExists checks a link target that is not an indexed page, against the
+// other routes and files of the site.
+func LinkExists(v *config.Values, target string) bool {
+	route := StripBasePath(v.BaseUrl(), target)
+	if route == "" {
+		// Outside of the site, so not ours to check
+		return true
+	}
+
+	for _, r := range Routes(&v.SiteConfig) {
+		if r.Matches(route) {
+			return true
+		}
+	}
+
+	// Static assets
+	if path.Ext(route) != "" {
+		return util.FileExists(filepath.Join(v.SitePath, filepath.FromSlash(route)))
+	}
+
+	return false
+}
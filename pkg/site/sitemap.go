@@ -0,0 +1,86 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// SitemapOptions are given for a page in its front matter.
+type SitemapOptions struct {
+	// From 0 to 1, or 0 to leave it to the crawler
+	Priority float64 `yaml:"priority"`
+	Exclude  bool    `yaml:"exclude"`
+}
+
+// SitemapUrl is an entry of a sitemap. Locations must be absolute.
+type SitemapUrl struct {
+	Loc      string  `xml:"loc"`
+	LastMod  string  `xml:"lastmod,omitempty"`
+	Priority float64 `xml:"priority,omitempty"`
+}
+
+// SitemapRef is an entry of a sitemap index, leading to one sitemap file.
+type SitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapUrlSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	Urls    []SitemapUrl `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []SitemapRef `xml:"sitemap"`
+}
+
+// SitemapPages lists the pages of the index that are not excluded from the
+// sitemap.
+func (i *PageIndex) SitemapPages() []*PageEntry {
+	var pages []*PageEntry
+	for _, p := range i.Pages {
+		if !p.Sitemap.Exclude {
+			pages = append(pages, p)
+		}
+	}
+
+	return pages
+}
+
+// LastMod formats a time for a sitemap.
+func LastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteSitemap writes a sitemap listing the given URLs.
+func WriteSitemap(w io.Writer, urls []SitemapUrl) error {
+	return writeXml(w, sitemapUrlSet{Urls: urls})
+}
+
+// WriteSitemapIndex writes a sitemap index, for sites split across several
+// sitemap files.
+func WriteSitemapIndex(w io.Writer, sitemaps []SitemapRef) error {
+	return writeXml(w, sitemapIndex{Sitemaps: sitemaps})
+}
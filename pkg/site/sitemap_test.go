@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type SitemapSuite struct {
+	suite.Suite
+}
+
+func TestSitemapSuite(t *testing.T) {
+	suite.Run(t, new(SitemapSuite))
+}
+
+func (s *SitemapSuite) TestSitemapPages() {
+	i := newPageIndex()
+	listed := &PageEntry{Url: "/listed"}
+	excluded := &PageEntry{Url: "/excluded", Sitemap: SitemapOptions{Exclude: true}}
+	i.Pages = []*PageEntry{listed, excluded}
+
+	s.Equal([]*PageEntry{listed}, i.SitemapPages())
+}
+
+func (s *SitemapSuite) TestLastMod() {
+	s.Equal("2020-03-01T08:30:00Z", LastMod(time.Date(2020, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))))
+	s.Equal("", LastMod(time.Time{}))
+}
+
+func (s *SitemapSuite) TestWriteSitemap() {
+	buf := bytes.Buffer{}
+	err := WriteSitemap(&buf, []SitemapUrl{
+		{Loc: "https://docs.example.com/a?b&c", LastMod: "2020-03-01T08:30:00Z", Priority: 0.8},
+		{Loc: "https://docs.example.com/d"},
+	})
+	s.Require().NoError(err)
+
+	s.Contains(buf.String(), `<loc>https://docs.example.com/a?b&amp;c</loc>`)
+	s.Contains(buf.String(), `<priority>0.8</priority>`)
+	s.Contains(buf.String(), "<url>\n    <loc>https://docs.example.com/d</loc>\n  </url>")
+}
+
+func (s *SitemapSuite) TestWriteSitemapIndex() {
+	buf := bytes.Buffer{}
+	err := WriteSitemapIndex(&buf, []SitemapRef{{Loc: "https://docs.example.com/sitemap/1.xml"}})
+	s.Require().NoError(err)
+
+	s.Contains(buf.String(), `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	s.Contains(buf.String(), `<sitemap>`+"\n    "+`<loc>https://docs.example.com/sitemap/1.xml</loc>`)
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/zpxio/mdsite/pkg/config"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Taxonomy groups the pages of a site by the terms given in their front
+// matter, such as tags.
+type Taxonomy struct {
+	Name  string
+	Title string
+	Url   string
+	Terms []*Term
+
+	// Terms by their slug
+	TermLookup map[string]*Term
+}
+
+type Term struct {
+	Name  string
+	Slug  string
+	Url   string
+	Pages []*PageEntry
+}
+
+// TaxonomyPage is the data for the taxonomy templates. Term is only set on
+// the page for a single term.
+type TaxonomyPage struct {
+	Taxonomy *Taxonomy
+	Term     *Term
+}
+
+// TermSlug creates the URL path segment for a term.
+func TermSlug(name string) string {
+	var slug []rune
+	dash := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if dash && len(slug) > 0 {
+				slug = append(slug, '-')
+			}
+			dash = false
+			slug = append(slug, unicode.ToLower(r))
+		default:
+			dash = true
+		}
+	}
+
+	return string(slug)
+}
+
+func (i *PageIndex) buildTaxonomies(v *config.Values) {
+	i.Taxonomies = make(map[string]*Taxonomy)
+
+	for _, tc := range v.SiteConfig.Taxonomies {
+		t := &Taxonomy{
+			Name:       tc.Name,
+			Title:      tc.Title,
+			Url:        v.SiteUrl("/" + tc.Path + "/"),
+			TermLookup: make(map[string]*Term),
+		}
+
+		for _, p := range i.Pages {
+			for _, name := range p.Terms[tc.Name] {
+				slug := TermSlug(name)
+				if slug == "" {
+					continue
+				}
+
+				term, ok := t.TermLookup[slug]
+				if !ok {
+					term = &Term{Name: name, Slug: slug, Url: t.Url + slug}
+					t.TermLookup[slug] = term
+					t.Terms = append(t.Terms, term)
+				}
+				if n := len(term.Pages); n == 0 || term.Pages[n-1] != p {
+					term.Pages = append(term.Pages, p)
+				}
+			}
+		}
+
+		sortTerms(t.Terms)
+		i.Taxonomies[t.Name] = t
+	}
+}
+
+func sortTerms(terms []*Term) {
+	sort.Slice(terms, func(a, b int) bool {
+		return strings.ToLower(terms[a].Name) < strings.ToLower(terms[b].Name)
+	})
+}
+
+// filter creates a copy of the taxonomy with only the pages of the index,
+// leaving out any terms without pages.
+func (t *Taxonomy) filter(i *PageIndex) *Taxonomy {
+	f := *t
+	f.Terms = nil
+	f.TermLookup = make(map[string]*Term)
+
+	for _, term := range t.Terms {
+		ft := *term
+		ft.Pages = nil
+		for _, p := range term.Pages {
+			if _, ok := i.PageLookup[p.Url]; ok {
+				ft.Pages = append(ft.Pages, p)
+			}
+		}
+
+		if len(ft.Pages) > 0 {
+			f.Terms = append(f.Terms, &ft)
+			f.TermLookup[ft.Slug] = &ft
+		}
+	}
+
+	return &f
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zpxio/mdsite/pkg/config"
+	"testing"
+)
+
+type TaxonomySuite struct {
+	suite.Suite
+	values *config.Values
+}
+
+func TestTaxonomySuite(t *testing.T) {
+	suite.Run(t, new(TaxonomySuite))
+}
+
+func (s *TaxonomySuite) SetupTest() {
+	s.values = loadTestValues(&s.Suite, "taxonomy01")
+}
+
+func (s *TaxonomySuite) urls(pages []*PageEntry) []string {
+	urls := make([]string, len(pages))
+	for n, p := range pages {
+		urls[n] = p.Url
+	}
+
+	return urls
+}
+
+func (s *TaxonomySuite) TestTermSlug() {
+	s.Equal("money-out", TermSlug("Money Out"))
+	s.Equal("c-sharp", TermSlug(" C# / Sharp?"))
+	s.Equal("", TermSlug("!?"))
+}
+
+func (s *TaxonomySuite) TestBuildTaxonomies() {
+	i, err := BuildIndex(s.values)
+	s.Require().NoError(err)
+	s.Require().Len(i.Taxonomies, 3)
+
+	tags := i.Taxonomies["tags"]
+	s.Equal("/tags/", tags.Url)
+	s.Require().Len(tags.Terms, 2)
+	// Terms are matched by their slug, and named as first seen
+	s.Equal("Billing", tags.Terms[0].Name)
+	s.Equal([]string{"/payouts", "/refunds"}, s.urls(tags.Terms[0].Pages))
+	s.Equal("/tags/money-out", tags.Terms[1].Url)
+
+	services := i.Taxonomies["service"]
+	s.Equal("/services/payments", services.TermLookup["payments"].Url)
+	s.Equal([]string{"/search"}, s.urls(services.TermLookup["search"].Pages))
+
+	s.Equal([]string{"/refunds", "/search"}, s.urls(i.Taxonomies["audience"].TermLookup["oncall"].Pages))
+	s.Nil(i.PageLookup["/plain"].Terms)
+}
+
+func (s *TaxonomySuite) TestFilterTaxonomies() {
+	i, err := BuildIndex(s.values)
+	s.Require().NoError(err)
+
+	f := i.Filter(func(p *PageEntry) bool {
+		return p.Url != "/search"
+	})
+
+	services := f.Taxonomies["service"]
+	s.Len(services.Terms, 1)
+	s.Nil(services.TermLookup["search"])
+	s.Len(i.Taxonomies["service"].Terms, 2)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// wikiNames lists the names a page can be found by in a wiki link: its
+// label, and its file name and path, each with or without the extension.
+func wikiNames(p *PageEntry) []string {
+	slashPath := filepath.ToSlash(p.Path)
+	file := path.Base(slashPath)
+	ext := path.Ext(slashPath)
+
+	candidates := []string{
+		p.Label,
+		file,
+		strings.TrimSuffix(file, ext),
+		slashPath,
+		strings.TrimSuffix(slashPath, ext),
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(candidates))
+	for _, n := range candidates {
+		n = wikiKey(n)
+		if n != "" && !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+
+	return names
+}
+
+func wikiKey(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "/"))
+}
+
+func (i *PageIndex) addWikiNames(p *PageEntry) {
+	for _, n := range wikiNames(p) {
+		i.WikiLookup[n] = append(i.WikiLookup[n], p)
+	}
+}
+
+// FindWikiPages finds the pages known by the name used in a wiki link.
+// Names are matched against the label, file name and path of every page,
+// ignoring case. More than one page means the name is ambiguous.
+func (i *PageIndex) FindWikiPages(name string) []*PageEntry {
+	return i.WikiLookup[wikiKey(name)]
+}
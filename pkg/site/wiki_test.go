@@ -0,0 +1,48 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package site
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type WikiSuite struct {
+	suite.Suite
+}
+
+func TestWikiSuite(t *testing.T) {
+	suite.Run(t, new(WikiSuite))
+}
+
+func (s *WikiSuite) TestFindWikiPages() {
+	i := newPageIndex()
+	setup := &PageEntry{Path: "dev/setup-guide.md", Label: "Setup Guide"}
+	first := &PageEntry{Path: "a/index.md", Label: "Index"}
+	second := &PageEntry{Path: "b/index.md", Label: "Index"}
+	for _, p := range []*PageEntry{setup, first, second} {
+		i.addWikiNames(p)
+	}
+
+	for _, name := range []string{"Setup Guide", "setup guide", "setup-guide", "setup-guide.md", "dev/setup-guide", "/dev/setup-guide.md"} {
+		s.Equal([]*PageEntry{setup}, i.FindWikiPages(name), name)
+	}
+
+	s.Equal([]*PageEntry{first}, i.FindWikiPages("a/index"))
+	s.Len(i.FindWikiPages("index"), 2)
+	s.Empty(i.FindWikiPages("unknown"))
+}
@@ -16,6 +16,26 @@
 
 package util
 
-func StringPrefix(s string, len int) string {
-	return string(([]rune(s))[0:len])
+// StringPrefix returns up to the first n characters of a string.
+func StringPrefix(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[0:n])
+}
+
+// UniqueStrings removes repeated values from a list, keeping the first of each.
+func UniqueStrings(values []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+
+	return out
 }
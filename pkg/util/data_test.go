@@ -14,43 +14,29 @@
  * limitations under the License.
  */
 
-package config
+package util
 
 import (
 	"github.com/stretchr/testify/suite"
 	"testing"
 )
 
-type GlobalSuite struct {
+type DataSuite struct {
 	suite.Suite
 }
 
-func TestGlobalSuite(t *testing.T) {
-	suite.Run(t, new(GlobalSuite))
+func TestDataSuite(t *testing.T) {
+	suite.Run(t, new(DataSuite))
 }
 
-func (s *GlobalSuite) TestUnregistered() {
-	globalConf = nil
-
-	s.Panics(func() {
-		Global()
-	})
-}
-
-func (s *GlobalSuite) TestNilRegistration() {
-	s.Panics(func() {
-		SetGlobal(nil)
-	})
+func (s *DataSuite) TestStringPrefix() {
+	s.Equal("abc", StringPrefix("abcdef", 3))
+	s.Equal("ab", StringPrefix("ab", 3))
+	s.Equal("", StringPrefix("", 3))
+	s.Equal("日本", StringPrefix("日本語", 2))
 }
 
-func (s *GlobalSuite) TestRegistration() {
-	v := Create()
-
-	var testPort uint16 = 4242
-	v.ListenPort = testPort
-
-	SetGlobal(v)
-
-	s.NotNil(Global())
-	s.Equal(testPort, Global().ListenPort)
+func (s *DataSuite) TestUniqueStrings() {
+	s.Equal([]string{"b", "a", "c"}, UniqueStrings([]string{"b", "a", "b", "c", "a"}))
+	s.Empty(UniqueStrings(nil))
 }
@@ -0,0 +1,37 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"path"
+	"strings"
+)
+
+// PathMatches checks a site path against a pattern. Patterns ending in a
+// slash match everything beneath them, patterns with wildcards are matched
+// as globs, and anything else must match exactly.
+func PathMatches(pattern string, p string) bool {
+	switch {
+	case strings.HasSuffix(pattern, "/"):
+		return strings.HasPrefix(p+"/", pattern)
+	case strings.ContainsAny(pattern, "*?["):
+		matched, _ := path.Match(pattern, p)
+		return matched
+	}
+
+	return pattern == p
+}
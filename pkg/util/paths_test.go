@@ -0,0 +1,39 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type PathsSuite struct {
+	suite.Suite
+}
+
+func TestPathsSuite(t *testing.T) {
+	suite.Run(t, new(PathsSuite))
+}
+
+func (s *PathsSuite) TestPathMatches() {
+	s.True(PathMatches("/ping", "/ping"))
+	s.False(PathMatches("/ping", "/ping/x"))
+	s.True(PathMatches("/public/", "/public"))
+	s.True(PathMatches("/public/", "/public/a/b"))
+	s.False(PathMatches("/public/", "/publicity"))
+	s.True(PathMatches("/*/open", "/public/open"))
+}
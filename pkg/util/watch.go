@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 zpxio (Jeff Sharpe)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"os"
+	"time"
+)
+
+// FileWatch tracks the modification state of a single file so callers can
+// cheaply poll it for changes.
+type FileWatch struct {
+	Path    string
+	modTime time.Time
+	size    int64
+}
+
+func WatchFile(path string) *FileWatch {
+	w := FileWatch{Path: path}
+	w.Changed()
+
+	return &w
+}
+
+// Changed reports whether the file has been modified since the last call. A
+// file which cannot be read is reported as unchanged.
+func (w *FileWatch) Changed() bool {
+	fs, err := os.Stat(w.Path)
+	if err != nil {
+		return false
+	}
+
+	if fs.ModTime().Equal(w.modTime) && fs.Size() == w.size {
+		return false
+	}
+
+	w.modTime = fs.ModTime()
+	w.size = fs.Size()
+
+	return true
+}